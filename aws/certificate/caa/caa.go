@@ -0,0 +1,174 @@
+package caa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/route53"
+	"github.com/pulumi/pulumi-cloudflare/sdk/v5/go/cloudflare"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	TagIssue     = "issue"
+	TagIssueWild = "issuewild"
+	TagIodef     = "iodef"
+
+	_ttl = 3600
+)
+
+// AmazonIssuers are the CAA domains ACM issues public certificates under.
+var AmazonIssuers = []string{
+	"amazon.com",
+	"amazontrust.com",
+	"awstrust.com",
+	"amazonaws.com",
+}
+
+type Policy struct {
+	// Issuers may issue any certificate for the domain, e.g. amazon.com or
+	// "letsencrypt.org; validationmethods=dns-01".
+	Issuers []string
+	// WildcardIssuers may issue wildcard certificates. When empty the
+	// Issuers also cover wildcards, unless ForbidWildcard is set.
+	WildcardIssuers []string
+	ForbidWildcard  bool
+	// Iodef are mailto: or http(s):// URLs CAs report policy violations to.
+	Iodef []string
+}
+
+type Record struct {
+	Flags int
+	Tag   string
+	Value string
+}
+
+// DefaultPolicy allows ACM to issue certificates, including wildcards.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Issuers: AmazonIssuers,
+	}
+}
+
+// Records returns one CAA record per issuer and reporting URL.
+func Records(policy *Policy) ([]*Record, error) {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+
+	if len(policy.Issuers) == 0 {
+		return nil, fmt.Errorf("caa policy needs at least one issuer")
+	}
+	if policy.ForbidWildcard && len(policy.WildcardIssuers) > 0 {
+		return nil, fmt.Errorf("caa policy cannot both forbid wildcards and list wildcard issuers")
+	}
+
+	records := []*Record{}
+	for _, issuer := range policy.Issuers {
+		if err := validateIssuer(issuer); err != nil {
+			return nil, err
+		}
+		records = append(records, &Record{Tag: TagIssue, Value: issuer})
+	}
+
+	for _, issuer := range policy.WildcardIssuers {
+		if err := validateIssuer(issuer); err != nil {
+			return nil, err
+		}
+		records = append(records, &Record{Tag: TagIssueWild, Value: issuer})
+	}
+	if policy.ForbidWildcard {
+		records = append(records, &Record{Tag: TagIssueWild, Value: ";"})
+	}
+
+	for _, url := range policy.Iodef {
+		if !strings.HasPrefix(url, "mailto:") && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return nil, fmt.Errorf("iodef %q must be a mailto: or http(s):// url", url)
+		}
+		records = append(records, &Record{Tag: TagIodef, Value: url})
+	}
+
+	return records, nil
+}
+
+// CreateRoute53Records writes the policy as a single CAA record set, since
+// Route53 keeps every value of a name and type in one set.
+func CreateRoute53Records(ctx *pulumi.Context, name string, zoneId string, domain string, policy *Policy) ([]pulumi.Resource, error) {
+	records, err := Records(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []string{}
+	for _, record := range records {
+		values = append(values, fmt.Sprintf("%d %s %q", record.Flags, record.Tag, record.Value))
+	}
+
+	recordSet, err := route53.NewRecord(ctx, name, &route53.RecordArgs{
+		ZoneId:         pulumi.String(zoneId),
+		Name:           pulumi.String(domain),
+		Type:           pulumi.String(route53.RecordTypeCAA),
+		Ttl:            pulumi.Int(_ttl),
+		Records:        pulumi.ToStringArray(values),
+		AllowOverwrite: pulumi.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []pulumi.Resource{recordSet}, nil
+}
+
+// CreateCloudflareRecords writes one Cloudflare record per CAA value.
+func CreateCloudflareRecords(ctx *pulumi.Context, name string, zoneId string, domain string, policy *Policy) ([]pulumi.Resource, error) {
+	records, err := Records(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []pulumi.Resource{}
+	for _, record := range records {
+		r, err := cloudflare.NewRecord(ctx, fmt.Sprintf("%s-%s-%s", name, record.Tag, recordName(record.Value)), &cloudflare.RecordArgs{
+			ZoneId: pulumi.String(zoneId),
+			Name:   pulumi.String(domain),
+			Type:   pulumi.String("CAA"),
+			Data: cloudflare.RecordDataArgs{
+				Flags: pulumi.StringPtr(fmt.Sprintf("%d", record.Flags)),
+				Tag:   pulumi.StringPtr(record.Tag),
+				Value: pulumi.StringPtr(record.Value),
+			},
+			Ttl:            pulumi.Int(_ttl),
+			AllowOverwrite: pulumi.BoolPtr(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+func validateIssuer(issuer string) error {
+	if strings.TrimSpace(issuer) == "" || strings.Contains(issuer, "\"") {
+		return fmt.Errorf("invalid caa issuer %q", issuer)
+	}
+
+	return nil
+}
+
+// recordName keeps logical names readable for values such as ";" or
+// mailto: URLs. A short hash of the full value keeps issuers that only differ
+// in their parameters, e.g. validationmethods or accounturi, apart.
+func recordName(value string) string {
+	if value == ";" {
+		return "none"
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	issuer := strings.SplitN(value, ";", 2)[0]
+	issuer = strings.NewReplacer(":", "-", "/", "-").Replace(strings.TrimSpace(issuer))
+	return fmt.Sprintf("%s-%s", issuer, hex.EncodeToString(sum[:4]))
+}
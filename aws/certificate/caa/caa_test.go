@@ -0,0 +1,124 @@
+package caa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *Policy
+		want    []*Record
+		wantErr string
+	}{
+		{
+			name:   "default policy",
+			policy: nil,
+			want: []*Record{
+				{Tag: TagIssue, Value: "amazon.com"},
+				{Tag: TagIssue, Value: "amazontrust.com"},
+				{Tag: TagIssue, Value: "awstrust.com"},
+				{Tag: TagIssue, Value: "amazonaws.com"},
+			},
+		},
+		{
+			name: "wildcard issuers and iodef",
+			policy: &Policy{
+				Issuers:         []string{"amazon.com"},
+				WildcardIssuers: []string{"letsencrypt.org; validationmethods=dns-01"},
+				Iodef:           []string{"mailto:security@example.com"},
+			},
+			want: []*Record{
+				{Tag: TagIssue, Value: "amazon.com"},
+				{Tag: TagIssueWild, Value: "letsencrypt.org; validationmethods=dns-01"},
+				{Tag: TagIodef, Value: "mailto:security@example.com"},
+			},
+		},
+		{
+			name:   "forbid wildcards",
+			policy: &Policy{Issuers: []string{"amazon.com"}, ForbidWildcard: true},
+			want: []*Record{
+				{Tag: TagIssue, Value: "amazon.com"},
+				{Tag: TagIssueWild, Value: ";"},
+			},
+		},
+		{
+			name:    "no issuers",
+			policy:  &Policy{},
+			wantErr: "at least one issuer",
+		},
+		{
+			name:    "forbid wildcards with wildcard issuers",
+			policy:  &Policy{Issuers: []string{"amazon.com"}, WildcardIssuers: []string{"amazon.com"}, ForbidWildcard: true},
+			wantErr: "cannot both forbid wildcards",
+		},
+		{
+			name:    "quoted issuer",
+			policy:  &Policy{Issuers: []string{`amazon.com"`}},
+			wantErr: "invalid caa issuer",
+		},
+		{
+			name:    "blank wildcard issuer",
+			policy:  &Policy{Issuers: []string{"amazon.com"}, WildcardIssuers: []string{" "}},
+			wantErr: "invalid caa issuer",
+		},
+		{
+			name:    "iodef without scheme",
+			policy:  &Policy{Issuers: []string{"amazon.com"}, Iodef: []string{"security@example.com"}},
+			wantErr: "must be a mailto: or http(s):// url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Records(tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordName(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		prefix string
+	}{
+		{name: "issuer", value: "amazon.com", prefix: "amazon.com-"},
+		{name: "forbid", value: ";", prefix: "none"},
+		{name: "mailto", value: "mailto:security@example.com", prefix: "mailto-security@example.com-"},
+		{name: "url", value: "https://example.com/caa", prefix: "https---example.com-caa-"},
+		{name: "parameters are dropped", value: "letsencrypt.org; validationmethods=dns-01", prefix: "letsencrypt.org-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordName(tt.value); !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("got %q, want prefix %q", got, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestRecordNameKeepsParametersApart(t *testing.T) {
+	a := recordName("letsencrypt.org; validationmethods=dns-01")
+	b := recordName("letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1")
+	if a == b {
+		t.Errorf("issuers with different parameters share the name %q", a)
+	}
+	if recordName("amazon.com") != recordName("amazon.com") {
+		t.Error("record names are not stable")
+	}
+}
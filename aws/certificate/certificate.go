@@ -0,0 +1,299 @@
+package certificate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/acm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+	"github.com/tungnt76/pulumi-in-go/dns"
+)
+
+type CertificateArgs struct {
+	// Name prefixes the logical name of every resource, so the package can be
+	// called once per certificate in the same stack.
+	Name   string
+	Domain string
+	// SubjectAlternativeNames are extra names on the same certificate.
+	SubjectAlternativeNames []string
+	// Wildcard adds *.Domain next to the apex Domain.
+	Wildcard    bool
+	Environment string
+	Tags        map[string]string
+	// Dns publishes CAA and validation records for public certificates in
+	// the zone that owns Domain. It is not used when PrivateCA is set.
+	Dns dns.Provider
+	// PrivateCA issues the certificate from an ACM Private CA instead of a
+	// public DNS-validated one, for internal load balancers on private
+	// domains. Use CreatePrivateCA once per stack or ExistingPrivateCA.
+	PrivateCA *PrivateCAOutput
+	// Import brings an existing certificate, e.g. from a corporate CA, into
+	// ACM instead of requesting one.
+	Import *ImportArgs
+	// CAA is published on Domain before the certificate is requested and
+	// must allow an Amazon issuer. Defaults to caa.DefaultPolicy. It is
+	// published once per domain, so certificates for the same domain in one
+	// program must agree on it.
+	CAA *caa.Policy
+}
+
+// publishedCAA remembers the CAA records of every domain per program, so
+// certificates sharing a domain do not overwrite each other's records.
+var publishedCAA = struct {
+	mu      sync.Mutex
+	domains map[*pulumi.Context]map[string]*caaRecords
+}{
+	domains: map[*pulumi.Context]map[string]*caaRecords{},
+}
+
+type caaRecords struct {
+	certificate string
+	policy      *caa.Policy
+	resources   []pulumi.Resource
+}
+
+type CertificateOutput struct {
+	CertificateArn pulumi.StringOutput
+	// ValidatedCertificateArn resolves once ACM has issued the certificate.
+	ValidatedCertificateArn pulumi.StringOutput
+	Domain                  string
+	SubjectAlternativeNames []string
+	CAAPolicy               *caa.Policy
+	// NotAfter is the expiry date, as reported by ACM.
+	NotAfter pulumi.StringOutput
+}
+
+func CreateCertificate(ctx *pulumi.Context, args *CertificateArgs) (*CertificateOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+	if args.Domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	// ACM reports names lowercased and without the trailing dot, which the
+	// validation options are matched against
+	normalized := *args
+	normalized.Domain = normalizeDomain(args.Domain)
+	args = &normalized
+
+	subjectAlternativeNames := subjectAlternativeNames(args)
+
+	if args.PrivateCA != nil && args.Import != nil {
+		return nil, fmt.Errorf("certificate %s: private ca and import are mutually exclusive", args.Name)
+	}
+	if args.PrivateCA != nil {
+		return createPrivateCertificate(ctx, args, subjectAlternativeNames)
+	}
+	if args.Import != nil {
+		return importCertificate(ctx, args, subjectAlternativeNames)
+	}
+
+	if args.Dns == nil {
+		return nil, fmt.Errorf("dns provider cannot be nil")
+	}
+
+	caaPolicy := args.CAA
+	if caaPolicy == nil {
+		caaPolicy = caa.DefaultPolicy()
+	}
+	if !allowsAmazon(caaPolicy.Issuers) {
+		return nil, fmt.Errorf("caa policy for %s does not allow any of %v", args.Domain, caa.AmazonIssuers)
+	}
+	if hasWildcard(args.Domain, subjectAlternativeNames) {
+		if caaPolicy.ForbidWildcard {
+			return nil, fmt.Errorf("caa policy for %s forbids the wildcard names of the certificate", args.Domain)
+		}
+		// issuewild records take precedence over issue for wildcard names
+		if len(caaPolicy.WildcardIssuers) > 0 && !allowsAmazon(caaPolicy.WildcardIssuers) {
+			return nil, fmt.Errorf("caa policy for %s does not allow any of %v to issue wildcards", args.Domain, caa.AmazonIssuers)
+		}
+	}
+
+	caaRecords, err := publishCAA(ctx, args, caaPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := acm.NewCertificate(ctx, args.Name, &acm.CertificateArgs{
+		DomainName:              pulumi.String(args.Domain),
+		SubjectAlternativeNames: pulumi.ToStringArray(subjectAlternativeNames),
+		ValidationMethod:        pulumi.String("DNS"),
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name":        args.Name,
+				"Environment": args.Environment,
+			}, args.Tags),
+		),
+	}, pulumi.DependsOn(caaRecords))
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are registered up front from the validation options so they
+	// show up in previews, and the validation waits on every one of them.
+	fqdns := pulumi.StringArray{}
+	for _, domain := range validationDomains(args.Domain, subjectAlternativeNames) {
+		option := validationOption(certificate, domain)
+		// ACM always validates with CNAME records
+		fqdn, err := args.Dns.CreateRecord(ctx, fmt.Sprintf("%s-validation-%s", args.Name, domain), &dns.Record{
+			Domain: option.ResourceRecordName().Elem(),
+			Type:   "CNAME",
+			Value:  option.ResourceRecordValue().Elem(),
+			Ttl:    60,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fqdns = append(fqdns, fqdn)
+	}
+
+	validation, err := acm.NewCertificateValidation(ctx, fmt.Sprintf("%s-validation", args.Name), &acm.CertificateValidationArgs{
+		CertificateArn:        certificate.Arn,
+		ValidationRecordFqdns: fqdns,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateOutput{
+		CertificateArn:          certificate.Arn,
+		ValidatedCertificateArn: validation.CertificateArn,
+		Domain:                  args.Domain,
+		SubjectAlternativeNames: subjectAlternativeNames,
+		CAAPolicy:               caaPolicy,
+		NotAfter:                certificate.NotAfter,
+	}, nil
+}
+
+// publishCAA publishes the policy on the certificate's domain, or returns the
+// records an earlier certificate for the same domain published.
+func publishCAA(ctx *pulumi.Context, args *CertificateArgs, policy *caa.Policy) ([]pulumi.Resource, error) {
+	publishedCAA.mu.Lock()
+	defer publishedCAA.mu.Unlock()
+
+	domains, ok := publishedCAA.domains[ctx]
+	if !ok {
+		domains = map[string]*caaRecords{}
+		publishedCAA.domains[ctx] = domains
+	}
+
+	if published, ok := domains[args.Domain]; ok {
+		if !reflect.DeepEqual(published.policy, policy) {
+			return nil, fmt.Errorf("certificate %s: caa policy for %s differs from the one certificate %s published", args.Name, args.Domain, published.certificate)
+		}
+		return published.resources, nil
+	}
+
+	resources, err := args.Dns.CAARecords(ctx, fmt.Sprintf("%s-caa", args.Name), args.Domain, policy)
+	if err != nil {
+		return nil, err
+	}
+	domains[args.Domain] = &caaRecords{
+		certificate: args.Name,
+		policy:      policy,
+		resources:   resources,
+	}
+
+	return resources, nil
+}
+
+func allowsAmazon(issuers []string) bool {
+	for _, issuer := range issuers {
+		for _, amazonIssuer := range caa.AmazonIssuers {
+			if strings.TrimSpace(strings.SplitN(issuer, ";", 2)[0]) == amazonIssuer {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func hasWildcard(domain string, subjectAlternativeNames []string) bool {
+	for _, name := range append([]string{domain}, subjectAlternativeNames...) {
+		if strings.HasPrefix(name, "*.") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validationDomains returns one name per validation record. A wildcard and
+// its apex are validated by the same CNAME, so they share an entry.
+func validationDomains(domain string, subjectAlternativeNames []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, name := range append([]string{domain}, subjectAlternativeNames...) {
+		name = strings.TrimPrefix(name, "*.")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// validationOption picks the validation option ACM returned for domain or its
+// wildcard.
+func validationOption(certificate *acm.Certificate, domain string) acm.CertificateDomainValidationOptionOutput {
+	return certificate.DomainValidationOptions.ApplyT(func(options []acm.CertificateDomainValidationOption) (acm.CertificateDomainValidationOption, error) {
+		for _, option := range options {
+			if option.DomainName != nil && strings.TrimPrefix(*option.DomainName, "*.") == domain {
+				return option, nil
+			}
+		}
+
+		return acm.CertificateDomainValidationOption{}, fmt.Errorf("no validation option for %s", domain)
+	}).(acm.CertificateDomainValidationOptionOutput)
+}
+
+// subjectAlternativeNames returns the SANs in the order given, without the
+// primary domain and without duplicates.
+func subjectAlternativeNames(args *CertificateArgs) []string {
+	names := append([]string{}, args.SubjectAlternativeNames...)
+	if args.Wildcard {
+		names = append(names, fmt.Sprintf("*.%s", args.Domain))
+	}
+
+	result := []string{}
+	seen := map[string]bool{normalizeDomain(args.Domain): true}
+	for _, name := range names {
+		name = normalizeDomain(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// normalizeDomain compares names the way DNS does, without case or a trailing
+// dot.
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
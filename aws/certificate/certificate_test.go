@@ -0,0 +1,266 @@
+package certificate
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+	"github.com/tungnt76/pulumi-in-go/dns"
+)
+
+// mocks answers ACM certificates with one validation option per name, the
+// way ACM does, and echoes the inputs of every other resource.
+type mocks struct{}
+
+func (mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	outputs := args.Inputs.Copy()
+	if args.TypeToken == "aws:acm/certificate:Certificate" {
+		names := []string{args.Inputs["domainName"].StringValue()}
+		if sans, ok := args.Inputs["subjectAlternativeNames"]; ok {
+			for _, san := range sans.ArrayValue() {
+				names = append(names, san.StringValue())
+			}
+		}
+
+		options := []resource.PropertyValue{}
+		for _, name := range names {
+			label := strings.TrimPrefix(name, "*.")
+			options = append(options, resource.NewObjectProperty(resource.PropertyMap{
+				"domainName":          resource.NewStringProperty(name),
+				"resourceRecordName":  resource.NewStringProperty(fmt.Sprintf("_x.%s.", label)),
+				"resourceRecordType":  resource.NewStringProperty("CNAME"),
+				"resourceRecordValue": resource.NewStringProperty(fmt.Sprintf("_y.%s.acm-validations.aws.", label)),
+			}))
+		}
+		outputs["domainValidationOptions"] = resource.NewArrayProperty(options)
+		outputs["arn"] = resource.NewStringProperty("arn:aws:acm:eu-west-1:123456789012:certificate/" + args.Name)
+	}
+
+	return args.Name + "-id", outputs, nil
+}
+
+func (mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestCreateCertificateValidationRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		args *CertificateArgs
+		want map[string]string
+	}{
+		{
+			name: "apex only",
+			args: &CertificateArgs{Name: "web", Domain: "example.com"},
+			want: map[string]string{
+				"web-validation-example.com": "_x.example.com.",
+			},
+		},
+		{
+			name: "wildcard shares the apex record",
+			args: &CertificateArgs{Name: "web", Domain: "example.com", Wildcard: true},
+			want: map[string]string{
+				"web-validation-example.com": "_x.example.com.",
+			},
+		},
+		{
+			name: "domain is normalized",
+			args: &CertificateArgs{Name: "web", Domain: "Example.COM.", SubjectAlternativeNames: []string{"example.com"}},
+			want: map[string]string{
+				"web-validation-example.com": "_x.example.com.",
+			},
+		},
+		{
+			name: "one record per san",
+			args: &CertificateArgs{Name: "web", Domain: "example.com", SubjectAlternativeNames: []string{"api.example.com", "EXAMPLE.com"}},
+			want: map[string]string{
+				"web-validation-example.com":     "_x.example.com.",
+				"web-validation-api.example.com": "_x.api.example.com.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := dns.NewFakeProvider("example.com")
+			tt.args.Dns = provider
+
+			var mu sync.Mutex
+			got := map[string]string{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				if _, err := CreateCertificate(ctx, tt.args); err != nil {
+					return err
+				}
+
+				for name, record := range provider.Records {
+					if record.Type != "CNAME" {
+						t.Errorf("record %s has type %s, want CNAME", name, record.Type)
+					}
+					name := name
+					record.Domain.ToStringOutput().ApplyT(func(domain string) string {
+						mu.Lock()
+						defer mu.Unlock()
+						got[name] = domain
+						return domain
+					})
+				}
+
+				return nil
+			}, pulumi.WithMocks("project", "stack", mocks{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got records %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCertificateRequiresDns(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := CreateCertificate(ctx, &CertificateArgs{Name: "web", Domain: "example.com"})
+		return err
+	}, pulumi.WithMocks("project", "stack", mocks{}))
+	if err == nil || !strings.Contains(err.Error(), "dns provider cannot be nil") {
+		t.Fatalf("got error %v, want a missing dns provider", err)
+	}
+}
+
+func TestValidationDomains(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain string
+		sans   []string
+		want   []string
+	}{
+		{name: "apex", domain: "example.com", want: []string{"example.com"}},
+		{name: "wildcard folds into apex", domain: "example.com", sans: []string{"*.example.com"}, want: []string{"example.com"}},
+		{name: "wildcard without apex", domain: "example.com", sans: []string{"*.api.example.com"}, want: []string{"example.com", "api.example.com"}},
+		{name: "order is kept", domain: "example.com", sans: []string{"b.example.com", "a.example.com"}, want: []string{"example.com", "b.example.com", "a.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validationDomains(tt.domain, tt.sans); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubjectAlternativeNames(t *testing.T) {
+	tests := []struct {
+		name string
+		args *CertificateArgs
+		want []string
+	}{
+		{name: "none", args: &CertificateArgs{Domain: "example.com"}, want: []string{}},
+		{name: "wildcard", args: &CertificateArgs{Domain: "example.com", Wildcard: true}, want: []string{"*.example.com"}},
+		{name: "duplicates and case", args: &CertificateArgs{Domain: "example.com", SubjectAlternativeNames: []string{"API.example.com", "api.example.com.", "Example.com"}}, want: []string{"api.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subjectAlternativeNames(tt.args)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCertificatePublishesCAAOncePerDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		certs   []*CertificateArgs
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "shared domain",
+			certs: []*CertificateArgs{
+				{Name: "web", Domain: "example.com"},
+				{Name: "api", Domain: "example.com.", SubjectAlternativeNames: []string{"api.example.com"}},
+			},
+			want: []string{"example.com"},
+		},
+		{
+			name: "separate domains",
+			certs: []*CertificateArgs{
+				{Name: "web", Domain: "example.com"},
+				{Name: "api", Domain: "api.example.com"},
+			},
+			want: []string{"api.example.com", "example.com"},
+		},
+		{
+			name: "conflicting policies",
+			certs: []*CertificateArgs{
+				{Name: "web", Domain: "example.com"},
+				{Name: "api", Domain: "example.com", CAA: &caa.Policy{Issuers: []string{"amazon.com"}}},
+			},
+			wantErr: "differs from the one certificate web published",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := dns.NewFakeProvider("example.com")
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				for _, args := range tt.certs {
+					args.Dns = provider
+					if _, err := CreateCertificate(ctx, args); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, pulumi.WithMocks("project", "stack", mocks{}))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for domain := range provider.CAA {
+				got = append(got, domain)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got caa records on %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "example.com", want: "example.com"},
+		{domain: "Example.COM.", want: "example.com"},
+		{domain: "*.API.example.com", want: "*.api.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := normalizeDomain(tt.domain); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
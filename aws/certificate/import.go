@@ -0,0 +1,183 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/acm"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// certificates expiring sooner than this are imported with a warning
+const _expiryWarning = 30 * 24 * time.Hour
+
+type ImportArgs struct {
+	// CertificateFile, PrivateKeyFile and ChainFile are local PEM files.
+	CertificateFile string
+	PrivateKeyFile  string
+	ChainFile       string
+
+	// Certificate, PrivateKey and Chain are PEM values used instead of the
+	// files, e.g. from config.RequireSecret.
+	Certificate pulumi.StringInput
+	PrivateKey  pulumi.StringInput
+	Chain       pulumi.StringInput
+}
+
+type importedPem struct {
+	certificate string
+	privateKey  string
+	chain       string
+}
+
+// importCertificate checks the PEM material against the requested names
+// before handing it to ACM, and exports the expiry date as <Name>-not-after.
+func importCertificate(ctx *pulumi.Context, args *CertificateArgs, subjectAlternativeNames []string) (*CertificateOutput, error) {
+	certificatePem, privateKeyPem, chainPem, err := importInputs(args.Import)
+	if err != nil {
+		return nil, fmt.Errorf("certificate %s: %w", args.Name, err)
+	}
+
+	names := append([]string{args.Domain}, subjectAlternativeNames...)
+
+	// the body only resolves once the checks pass, so ACM never sees material
+	// that failed them
+	certificateBody := pulumi.All(certificatePem, privateKeyPem, chainPem).ApplyT(func(values []interface{}) (string, error) {
+		imported := importedPem{
+			certificate: values[0].(string),
+			privateKey:  values[1].(string),
+			chain:       values[2].(string),
+		}
+
+		leaf, err := validatePem(imported, names)
+		if err != nil {
+			return "", fmt.Errorf("certificate %s: %w", args.Name, err)
+		}
+		if time.Until(leaf.NotAfter) < _expiryWarning {
+			ctx.Log.Warn(fmt.Sprintf("certificate %s expires on %s", args.Name, leaf.NotAfter.Format(time.RFC3339)), nil)
+		}
+
+		return imported.certificate, nil
+	}).(pulumi.StringOutput)
+
+	var certificateChain pulumi.StringPtrInput
+	if args.Import.ChainFile != "" || args.Import.Chain != nil {
+		certificateChain = chainPem.ToStringOutput()
+	}
+
+	certificate, err := acm.NewCertificate(ctx, args.Name, &acm.CertificateArgs{
+		CertificateBody:  certificateBody,
+		PrivateKey:       pulumi.ToSecret(privateKeyPem).(pulumi.StringOutput),
+		CertificateChain: certificateChain,
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name":        args.Name,
+				"Environment": args.Environment,
+			}, args.Tags),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Export(fmt.Sprintf("%s-not-after", args.Name), certificate.NotAfter)
+
+	return &CertificateOutput{
+		CertificateArn:          certificate.Arn,
+		ValidatedCertificateArn: certificate.Arn,
+		Domain:                  args.Domain,
+		SubjectAlternativeNames: subjectAlternativeNames,
+		NotAfter:                certificate.NotAfter,
+	}, nil
+}
+
+// importInputs reads the PEM files, falling back to the given values.
+func importInputs(args *ImportArgs) (certificate, privateKey, chain pulumi.StringInput, err error) {
+	certificate, err = pemInput(args.CertificateFile, args.Certificate)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if certificate == nil {
+		return nil, nil, nil, fmt.Errorf("import needs a certificate file or value")
+	}
+
+	privateKey, err = pemInput(args.PrivateKeyFile, args.PrivateKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if privateKey == nil {
+		return nil, nil, nil, fmt.Errorf("import needs a private key file or value")
+	}
+
+	chain, err = pemInput(args.ChainFile, args.Chain)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if chain == nil {
+		chain = pulumi.String("")
+	}
+
+	return certificate, privateKey, chain, nil
+}
+
+func pemInput(file string, value pulumi.StringInput) (pulumi.StringInput, error) {
+	if file == "" {
+		return value, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return pulumi.String(string(content)), nil
+}
+
+// validatePem parses the certificate and returns it if it is currently valid,
+// covers every name and matches the private key.
+func validatePem(imported importedPem, names []string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(imported.certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("certificate is not a PEM encoded certificate")
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Before(leaf.NotBefore) {
+		return nil, fmt.Errorf("certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	for _, name := range names {
+		if err := leaf.VerifyHostname(name); err != nil {
+			return nil, fmt.Errorf("certificate does not cover %s: %w", name, err)
+		}
+	}
+
+	if _, err := tls.X509KeyPair([]byte(imported.certificate), []byte(imported.privateKey)); err != nil {
+		return nil, fmt.Errorf("private key does not match the certificate: %w", err)
+	}
+
+	rest := []byte(imported.chain)
+	for {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("certificate chain: %w", err)
+		}
+	}
+
+	return leaf, nil
+}
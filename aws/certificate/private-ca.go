@@ -0,0 +1,163 @@
+package certificate
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/acm"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/acmpca"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	_privateCAKeyAlgorithm     = "RSA_4096"
+	_privateCASigningAlgorithm = "SHA512WITHRSA"
+)
+
+type PrivateCAArgs struct {
+	CommonName    string
+	Organization  string
+	ValidityYears int
+	// PermanentDeletionTimeInDays is how long a deleted CA can be restored
+	// (7-30), defaulting to 30.
+	PermanentDeletionTimeInDays int
+}
+
+type PrivateCAOutput struct {
+	CertificateAuthorityArn pulumi.StringOutput
+	// Certificate is the CA's PEM certificate, for clients that need to
+	// trust it.
+	Certificate pulumi.StringOutput
+	// Resources must exist before the CA can issue certificates.
+	Resources []pulumi.Resource
+}
+
+// CreatePrivateCA creates a root CA, self-signs and installs its certificate
+// and lets ACM renew the certificates it issues. A private CA is billed per
+// month, so create it once and share the output between certificates.
+func CreatePrivateCA(ctx *pulumi.Context, name string, args *PrivateCAArgs) (*PrivateCAOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.CommonName == "" {
+		return nil, fmt.Errorf("private ca %s: common name cannot be empty", name)
+	}
+
+	validityYears := args.ValidityYears
+	if validityYears == 0 {
+		validityYears = 10
+	}
+
+	permanentDeletionTimeInDays := args.PermanentDeletionTimeInDays
+	if permanentDeletionTimeInDays == 0 {
+		permanentDeletionTimeInDays = 30
+	}
+	if permanentDeletionTimeInDays < 7 || permanentDeletionTimeInDays > 30 {
+		return nil, fmt.Errorf("private ca %s: permanent deletion time must be between 7 and 30 days, got %d", name, permanentDeletionTimeInDays)
+	}
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := &acmpca.CertificateAuthorityCertificateAuthorityConfigurationSubjectArgs{
+		CommonName: pulumi.String(args.CommonName),
+	}
+	if args.Organization != "" {
+		subject.Organization = pulumi.String(args.Organization)
+	}
+
+	authority, err := acmpca.NewCertificateAuthority(ctx, name, &acmpca.CertificateAuthorityArgs{
+		Type: pulumi.String("ROOT"),
+		CertificateAuthorityConfiguration: &acmpca.CertificateAuthorityCertificateAuthorityConfigurationArgs{
+			KeyAlgorithm:     pulumi.String(_privateCAKeyAlgorithm),
+			SigningAlgorithm: pulumi.String(_privateCASigningAlgorithm),
+			Subject:          subject,
+		},
+		PermanentDeletionTimeInDays: pulumi.Int(permanentDeletionTimeInDays),
+		Tags: pulumi.ToStringMap(map[string]string{
+			"Name": name,
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rootCertificate, err := acmpca.NewCertificate(ctx, fmt.Sprintf("%s-root", name), &acmpca.CertificateArgs{
+		CertificateAuthorityArn:   authority.Arn,
+		CertificateSigningRequest: authority.CertificateSigningRequest,
+		SigningAlgorithm:          pulumi.String(_privateCASigningAlgorithm),
+		TemplateArn:               pulumi.String(fmt.Sprintf("arn:%s:acm-pca:::template/RootCACertificate/V1", partition.Partition)),
+		Validity: &acmpca.CertificateValidityArgs{
+			Type:  pulumi.String("YEARS"),
+			Value: pulumi.String(fmt.Sprintf("%d", validityYears)),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	installation, err := acmpca.NewCertificateAuthorityCertificate(ctx, fmt.Sprintf("%s-root", name), &acmpca.CertificateAuthorityCertificateArgs{
+		CertificateAuthorityArn: authority.Arn,
+		Certificate:             rootCertificate.Certificate,
+		CertificateChain:        rootCertificate.CertificateChain,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := acmpca.NewPermission(ctx, fmt.Sprintf("%s-acm", name), &acmpca.PermissionArgs{
+		CertificateAuthorityArn: authority.Arn,
+		Principal:               pulumi.String("acm.amazonaws.com"),
+		Actions: pulumi.ToStringArray([]string{
+			"IssueCertificate",
+			"GetCertificate",
+			"ListPermissions",
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateCAOutput{
+		CertificateAuthorityArn: authority.Arn,
+		Certificate:             rootCertificate.Certificate,
+		Resources:               []pulumi.Resource{installation, permission},
+	}, nil
+}
+
+// ExistingPrivateCA references an active private CA managed elsewhere, which
+// must already allow ACM to issue and renew certificates.
+func ExistingPrivateCA(arn string) *PrivateCAOutput {
+	return &PrivateCAOutput{
+		CertificateAuthorityArn: pulumi.String(arn).ToStringOutput(),
+	}
+}
+
+// createPrivateCertificate requests a certificate from a private CA. These
+// are issued without DNS validation or CAA records.
+func createPrivateCertificate(ctx *pulumi.Context, args *CertificateArgs, subjectAlternativeNames []string) (*CertificateOutput, error) {
+	certificate, err := acm.NewCertificate(ctx, args.Name, &acm.CertificateArgs{
+		DomainName:              pulumi.String(args.Domain),
+		SubjectAlternativeNames: pulumi.ToStringArray(subjectAlternativeNames),
+		CertificateAuthorityArn: args.PrivateCA.CertificateAuthorityArn,
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name":        args.Name,
+				"Environment": args.Environment,
+			}, args.Tags),
+		),
+	}, pulumi.DependsOn(args.PrivateCA.Resources))
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateOutput{
+		CertificateArn:          certificate.Arn,
+		ValidatedCertificateArn: certificate.Arn,
+		Domain:                  args.Domain,
+		SubjectAlternativeNames: subjectAlternativeNames,
+		NotAfter:                certificate.NotAfter,
+	}, nil
+}
@@ -0,0 +1,319 @@
+package ecr
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecr"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+const (
+	_defaultKeepTagged         = 30
+	_defaultUntaggedExpireDays = 14
+)
+
+var (
+	_repositoryName = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*$`)
+	_accountId      = regexp.MustCompile(`^[0-9]{12}$`)
+)
+
+type Repository struct {
+	Name string `json:"name"`
+	// MutableTags allows pushing over an existing tag. Tags are immutable by
+	// default.
+	MutableTags bool `json:"mutable_tags"`
+	// ScanOnPush defaults to true.
+	ScanOnPush *bool `json:"scan_on_push"`
+	// KmsKeyArn overrides RepositoriesArgs.KmsKeyArn.
+	KmsKeyArn string `json:"kms_key_arn"`
+	// KeepTagged is how many tagged images are kept, defaulting to 30.
+	KeepTagged int `json:"keep_tagged"`
+	// UntaggedExpireDays is how long untagged images are kept, defaulting
+	// to 14.
+	UntaggedExpireDays int `json:"untagged_expire_days"`
+	// PullAccountIds are other AWS accounts allowed to pull images.
+	PullAccountIds []string `json:"pull_account_ids"`
+	// ForceDelete deletes the repository even when it still holds images.
+	ForceDelete bool `json:"force_delete"`
+}
+
+type RepositoriesArgs struct {
+	Environment string
+	Tags        map[string]string
+	// KmsKeyArn encrypts the repositories. When empty the AWS managed
+	// aws/ecr key is used.
+	KmsKeyArn             string
+	Repositories          []*Repository
+	PullThroughCacheRules []*PullThroughCacheRule
+}
+
+type RepositoryOutput struct {
+	Arn           pulumi.StringOutput
+	Name          pulumi.StringOutput
+	RepositoryUrl pulumi.StringOutput
+}
+
+type RepositoriesOutput struct {
+	RegistryId   string
+	Repositories map[string]*RepositoryOutput
+}
+
+// RepositoriesFromConfig reads the repositories under
+// `<namespace>:repositories`.
+func RepositoriesFromConfig(ctx *pulumi.Context, namespace string) ([]*Repository, error) {
+	repositories := []*Repository{}
+	if err := config.New(ctx, namespace).GetObject("repositories", &repositories); err != nil {
+		return nil, err
+	}
+
+	return repositories, nil
+}
+
+func CreateRepositories(ctx *pulumi.Context, args *RepositoriesArgs) (*RepositoriesOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	names := map[string]bool{}
+	for _, repository := range args.Repositories {
+		setRepositoryDefaults(repository)
+		if err := validateRepository(repository); err != nil {
+			return nil, fmt.Errorf("ecr repository %s: %w", repository.Name, err)
+		}
+		if names[repository.Name] {
+			return nil, fmt.Errorf("ecr repository %s is defined more than once", repository.Name)
+		}
+		names[repository.Name] = true
+	}
+
+	caller, err := aws.GetCallerIdentity(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &RepositoriesOutput{
+		RegistryId:   caller.AccountId,
+		Repositories: map[string]*RepositoryOutput{},
+	}
+
+	for _, repository := range args.Repositories {
+		tags := merge(map[string]string{
+			"Name":        repository.Name,
+			"Environment": args.Environment,
+		}, args.Tags)
+
+		tagMutability := "IMMUTABLE"
+		if repository.MutableTags {
+			tagMutability = "MUTABLE"
+		}
+
+		kmsKeyArn := args.KmsKeyArn
+		if repository.KmsKeyArn != "" {
+			kmsKeyArn = repository.KmsKeyArn
+		}
+		encryption := &ecr.RepositoryEncryptionConfigurationArgs{
+			EncryptionType: pulumi.String("KMS"),
+		}
+		if kmsKeyArn != "" {
+			encryption.KmsKey = pulumi.String(kmsKeyArn)
+		}
+
+		r, err := ecr.NewRepository(ctx, fmt.Sprintf("%s-ecr", repository.Name), &ecr.RepositoryArgs{
+			Name:               pulumi.String(repository.Name),
+			ImageTagMutability: pulumi.String(tagMutability),
+			ImageScanningConfiguration: &ecr.RepositoryImageScanningConfigurationArgs{
+				ScanOnPush: pulumi.Bool(*repository.ScanOnPush),
+			},
+			EncryptionConfigurations: ecr.RepositoryEncryptionConfigurationArray{
+				encryption,
+			},
+			ForceDelete: pulumi.Bool(repository.ForceDelete),
+			Tags:        pulumi.ToStringMap(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		policy, err := lifecyclePolicy(repository.KeepTagged, repository.UntaggedExpireDays)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = ecr.NewLifecyclePolicy(ctx, fmt.Sprintf("%s-ecr-lifecycle", repository.Name), &ecr.LifecyclePolicyArgs{
+			Repository: r.Name,
+			Policy:     pulumi.String(policy),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(repository.PullAccountIds) > 0 {
+			policy, err := pullPolicy(partition.Partition, repository.PullAccountIds)
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = ecr.NewRepositoryPolicy(ctx, fmt.Sprintf("%s-ecr-policy", repository.Name), &ecr.RepositoryPolicyArgs{
+				Repository: r.Name,
+				Policy:     pulumi.String(policy),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		output.Repositories[repository.Name] = &RepositoryOutput{
+			Arn:           r.Arn,
+			Name:          r.Name,
+			RepositoryUrl: r.RepositoryUrl,
+		}
+	}
+
+	err = createPullThroughCacheRules(ctx, args.PullThroughCacheRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func setRepositoryDefaults(repository *Repository) {
+	if repository.ScanOnPush == nil {
+		scanOnPush := true
+		repository.ScanOnPush = &scanOnPush
+	}
+	if repository.KeepTagged == 0 {
+		repository.KeepTagged = _defaultKeepTagged
+	}
+	if repository.UntaggedExpireDays == 0 {
+		repository.UntaggedExpireDays = _defaultUntaggedExpireDays
+	}
+}
+
+func validateRepository(repository *Repository) error {
+	if !_repositoryName.MatchString(repository.Name) || len(repository.Name) < 2 || len(repository.Name) > 256 {
+		return fmt.Errorf("invalid repository name %q", repository.Name)
+	}
+	if repository.KeepTagged < 1 {
+		return fmt.Errorf("keep tagged must be at least 1, got %d", repository.KeepTagged)
+	}
+	if repository.UntaggedExpireDays < 1 {
+		return fmt.Errorf("untagged expire days must be at least 1, got %d", repository.UntaggedExpireDays)
+	}
+	for _, accountId := range repository.PullAccountIds {
+		if !_accountId.MatchString(accountId) {
+			return fmt.Errorf("invalid pull account id %q", accountId)
+		}
+	}
+
+	return nil
+}
+
+type lifecycleRule struct {
+	RulePriority int    `json:"rulePriority"`
+	Description  string `json:"description"`
+	Selection    struct {
+		TagStatus      string   `json:"tagStatus"`
+		TagPatternList []string `json:"tagPatternList,omitempty"`
+		CountType      string   `json:"countType"`
+		CountUnit      string   `json:"countUnit,omitempty"`
+		CountNumber    int      `json:"countNumber"`
+	} `json:"selection"`
+	Action struct {
+		Type string `json:"type"`
+	} `json:"action"`
+}
+
+// lifecyclePolicy expires untagged images after untaggedExpireDays and keeps
+// the newest keepTagged tagged images.
+func lifecyclePolicy(keepTagged int, untaggedExpireDays int) (string, error) {
+	untagged := lifecycleRule{
+		RulePriority: 1,
+		Description:  fmt.Sprintf("Expire untagged images after %d days", untaggedExpireDays),
+	}
+	untagged.Selection.TagStatus = "untagged"
+	untagged.Selection.CountType = "sinceImagePushed"
+	untagged.Selection.CountUnit = "days"
+	untagged.Selection.CountNumber = untaggedExpireDays
+	untagged.Action.Type = "expire"
+
+	tagged := lifecycleRule{
+		RulePriority: 2,
+		Description:  fmt.Sprintf("Keep the last %d tagged images", keepTagged),
+	}
+	tagged.Selection.TagStatus = "tagged"
+	tagged.Selection.TagPatternList = []string{"*"}
+	tagged.Selection.CountType = "imageCountMoreThan"
+	tagged.Selection.CountNumber = keepTagged
+	tagged.Action.Type = "expire"
+
+	document, err := json.Marshal(map[string]interface{}{
+		"rules": []lifecycleRule{untagged, tagged},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(document), nil
+}
+
+// pullPolicy lets the given accounts pull images from a repository.
+func pullPolicy(partition string, accountIds []string) (string, error) {
+	principals := []string{}
+	for _, accountId := range accountIds {
+		principals = append(principals, fmt.Sprintf("arn:%s:iam::%s:root", partition, accountId))
+	}
+
+	document, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Sid":    "CrossAccountPull",
+				"Effect": "Allow",
+				"Principal": map[string]interface{}{
+					"AWS": principals,
+				},
+				"Action": []string{
+					"ecr:BatchCheckLayerAvailability",
+					"ecr:BatchGetImage",
+					"ecr:GetDownloadUrlForLayer",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(document), nil
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package ecr
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLifecyclePolicy(t *testing.T) {
+	document, err := lifecyclePolicy(30, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var policy struct {
+		Rules []map[string]interface{} `json:"rules"`
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		t.Fatalf("policy is not valid json: %v", err)
+	}
+
+	want := []map[string]interface{}{
+		{
+			"rulePriority": float64(1),
+			"description":  "Expire untagged images after 14 days",
+			"selection": map[string]interface{}{
+				"tagStatus":   "untagged",
+				"countType":   "sinceImagePushed",
+				"countUnit":   "days",
+				"countNumber": float64(14),
+			},
+			"action": map[string]interface{}{"type": "expire"},
+		},
+		{
+			"rulePriority": float64(2),
+			"description":  "Keep the last 30 tagged images",
+			"selection": map[string]interface{}{
+				"tagStatus":      "tagged",
+				"tagPatternList": []interface{}{"*"},
+				"countType":      "imageCountMoreThan",
+				"countNumber":    float64(30),
+			},
+			"action": map[string]interface{}{"type": "expire"},
+		},
+	}
+	if !reflect.DeepEqual(policy.Rules, want) {
+		t.Errorf("got rules %v, want %v", policy.Rules, want)
+	}
+}
+
+func TestPullPolicy(t *testing.T) {
+	document, err := pullPolicy("aws", []string{"111111111111", "222222222222"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, principal := range []string{"arn:aws:iam::111111111111:root", "arn:aws:iam::222222222222:root"} {
+		if !strings.Contains(document, principal) {
+			t.Errorf("policy %s does not allow %s", document, principal)
+		}
+	}
+	if strings.Contains(document, "ecr:Put") {
+		t.Errorf("policy %s allows pushing", document)
+	}
+}
+
+func TestValidateRepository(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository Repository
+		wantErr    string
+	}{
+		{name: "simple", repository: Repository{Name: "api"}},
+		{name: "namespaced", repository: Repository{Name: "team/api-server", PullAccountIds: []string{"123456789012"}}},
+		{name: "upper case", repository: Repository{Name: "API"}, wantErr: "invalid repository name"},
+		{name: "too short", repository: Repository{Name: "a"}, wantErr: "invalid repository name"},
+		{name: "trailing separator", repository: Repository{Name: "api-"}, wantErr: "invalid repository name"},
+		{name: "empty path segment", repository: Repository{Name: "team//api"}, wantErr: "invalid repository name"},
+		{name: "negative keep tagged", repository: Repository{Name: "api", KeepTagged: -1}, wantErr: "keep tagged must be at least 1"},
+		{name: "negative expiry", repository: Repository{Name: "api", UntaggedExpireDays: -1}, wantErr: "untagged expire days must be at least 1"},
+		{name: "invalid account", repository: Repository{Name: "api", PullAccountIds: []string{"12345"}}, wantErr: "invalid pull account id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := tt.repository
+			setRepositoryDefaults(&repository)
+			err := validateRepository(&repository)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePullThroughCacheRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    PullThroughCacheRule
+		wantErr string
+	}{
+		{name: "public registry", rule: PullThroughCacheRule{Prefix: "ecr-public", UpstreamRegistryUrl: "public.ecr.aws"}},
+		{
+			name: "docker hub with credential",
+			rule: PullThroughCacheRule{
+				Prefix:              "docker-hub",
+				UpstreamRegistryUrl: "registry-1.docker.io",
+				CredentialArn:       "arn:aws:secretsmanager:eu-west-1:123456789012:secret:ecr-pullthroughcache/docker-hub-AbCdEf",
+			},
+		},
+		{name: "invalid prefix", rule: PullThroughCacheRule{Prefix: "Docker", UpstreamRegistryUrl: "public.ecr.aws"}, wantErr: "invalid prefix"},
+		{name: "scheme", rule: PullThroughCacheRule{Prefix: "k8s", UpstreamRegistryUrl: "https://registry.k8s.io"}, wantErr: "must not have a scheme"},
+		{name: "docker hub without credential", rule: PullThroughCacheRule{Prefix: "docker-hub", UpstreamRegistryUrl: "registry-1.docker.io"}, wantErr: "needs a credential arn"},
+		{
+			name: "credential outside the prefix",
+			rule: PullThroughCacheRule{
+				Prefix:              "ghcr",
+				UpstreamRegistryUrl: "ghcr.io",
+				CredentialArn:       "arn:aws:secretsmanager:eu-west-1:123456789012:secret:github-AbCdEf",
+			},
+			wantErr: "must be a secret named ecr-pullthroughcache/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePullThroughCacheRule(&tt.rule)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package ecr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecr"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// _credentialRegistries are the upstream registries ECR only caches from with
+// a Secrets Manager credential.
+var _credentialRegistries = []string{
+	"registry-1.docker.io",
+	"ghcr.io",
+	"registry.gitlab.com",
+}
+
+var _cachePrefix = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// PullThroughCacheRule caches images of an upstream registry under
+// `<registry>/<prefix>/...`. The cached repositories are created by ECR on
+// first pull.
+type PullThroughCacheRule struct {
+	Prefix string `json:"prefix"`
+	// UpstreamRegistryUrl is e.g. public.ecr.aws, registry.k8s.io or
+	// registry-1.docker.io.
+	UpstreamRegistryUrl string `json:"upstream_registry_url"`
+	// CredentialArn is a Secrets Manager secret whose name starts with
+	// ecr-pullthroughcache/. Docker Hub, GitHub and GitLab need one.
+	CredentialArn string `json:"credential_arn"`
+}
+
+// PullThroughCacheRulesFromConfig reads the rules under
+// `<namespace>:pull_through_cache_rules`.
+func PullThroughCacheRulesFromConfig(ctx *pulumi.Context, namespace string) ([]*PullThroughCacheRule, error) {
+	rules := []*PullThroughCacheRule{}
+	if err := config.New(ctx, namespace).GetObject("pull_through_cache_rules", &rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func createPullThroughCacheRules(ctx *pulumi.Context, rules []*PullThroughCacheRule) error {
+	prefixes := map[string]bool{}
+	for _, rule := range rules {
+		if err := validatePullThroughCacheRule(rule); err != nil {
+			return fmt.Errorf("pull through cache rule %s: %w", rule.Prefix, err)
+		}
+		if prefixes[rule.Prefix] {
+			return fmt.Errorf("pull through cache rule %s is defined more than once", rule.Prefix)
+		}
+		prefixes[rule.Prefix] = true
+	}
+
+	for _, rule := range rules {
+		ruleArgs := &ecr.PullThroughCacheRuleArgs{
+			EcrRepositoryPrefix: pulumi.String(rule.Prefix),
+			UpstreamRegistryUrl: pulumi.String(rule.UpstreamRegistryUrl),
+		}
+		if rule.CredentialArn != "" {
+			ruleArgs.CredentialArn = pulumi.String(rule.CredentialArn)
+		}
+
+		_, err := ecr.NewPullThroughCacheRule(ctx, fmt.Sprintf("%s-ecr-cache", rule.Prefix), ruleArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePullThroughCacheRule(rule *PullThroughCacheRule) error {
+	if !_cachePrefix.MatchString(rule.Prefix) || len(rule.Prefix) < 2 || len(rule.Prefix) > 30 {
+		return fmt.Errorf("invalid prefix %q", rule.Prefix)
+	}
+	if rule.UpstreamRegistryUrl == "" {
+		return fmt.Errorf("upstream registry url cannot be empty")
+	}
+	if strings.Contains(rule.UpstreamRegistryUrl, "://") {
+		return fmt.Errorf("upstream registry url %q must not have a scheme", rule.UpstreamRegistryUrl)
+	}
+	if rule.CredentialArn == "" && contains(_credentialRegistries, rule.UpstreamRegistryUrl) {
+		return fmt.Errorf("upstream registry %s needs a credential arn", rule.UpstreamRegistryUrl)
+	}
+	if rule.CredentialArn != "" && !strings.Contains(rule.CredentialArn, ":secret:ecr-pullthroughcache/") {
+		return fmt.Errorf("credential %q must be a secret named ecr-pullthroughcache/...", rule.CredentialArn)
+	}
+
+	return nil
+}
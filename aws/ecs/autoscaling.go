@@ -0,0 +1,130 @@
+package ecs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/appautoscaling"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	targetgroup "github.com/tungnt76/pulumi-in-go/aws/elb/target-group"
+)
+
+type Autoscaling struct {
+	MinCapacity int
+	MaxCapacity int
+	// CpuTarget is the average CPU utilisation in percent to track; zero
+	// disables CPU scaling.
+	CpuTarget int
+	// RequestsPerTarget is the ALB request count per task to track; zero
+	// disables request count scaling. It needs LoadBalancer.LoadBalancerArn.
+	RequestsPerTarget int
+	// ScaleInCooldown and ScaleOutCooldown are in seconds, defaulting to 300
+	// and 60.
+	ScaleInCooldown  int
+	ScaleOutCooldown int
+}
+
+func createAutoscaling(ctx *pulumi.Context, args *ServiceArgs, clusterName pulumi.StringOutput, serviceName pulumi.StringOutput, targetGroup *targetgroup.TargetGroupOutput) error {
+	autoscaling := args.Autoscaling
+
+	scaleInCooldown := autoscaling.ScaleInCooldown
+	if scaleInCooldown == 0 {
+		scaleInCooldown = 300
+	}
+	scaleOutCooldown := autoscaling.ScaleOutCooldown
+	if scaleOutCooldown == 0 {
+		scaleOutCooldown = 60
+	}
+
+	target, err := appautoscaling.NewTarget(ctx, fmt.Sprintf("%s-scaling", args.Name), &appautoscaling.TargetArgs{
+		MinCapacity:       pulumi.Int(autoscaling.MinCapacity),
+		MaxCapacity:       pulumi.Int(autoscaling.MaxCapacity),
+		ResourceId:        pulumi.Sprintf("service/%s/%s", clusterName, serviceName),
+		ScalableDimension: pulumi.String("ecs:service:DesiredCount"),
+		ServiceNamespace:  pulumi.String("ecs"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if autoscaling.CpuTarget > 0 {
+		_, err = appautoscaling.NewPolicy(ctx, fmt.Sprintf("%s-scaling-cpu", args.Name), &appautoscaling.PolicyArgs{
+			Name:              pulumi.String(fmt.Sprintf("%s-cpu", args.Name)),
+			PolicyType:        pulumi.String("TargetTrackingScaling"),
+			ResourceId:        target.ResourceId,
+			ScalableDimension: target.ScalableDimension,
+			ServiceNamespace:  target.ServiceNamespace,
+			TargetTrackingScalingPolicyConfiguration: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationArgs{
+				TargetValue:      pulumi.Float64(float64(autoscaling.CpuTarget)),
+				ScaleInCooldown:  pulumi.Int(scaleInCooldown),
+				ScaleOutCooldown: pulumi.Int(scaleOutCooldown),
+				PredefinedMetricSpecification: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationPredefinedMetricSpecificationArgs{
+					PredefinedMetricType: pulumi.String("ECSServiceAverageCPUUtilization"),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if autoscaling.RequestsPerTarget > 0 {
+		_, err = appautoscaling.NewPolicy(ctx, fmt.Sprintf("%s-scaling-requests", args.Name), &appautoscaling.PolicyArgs{
+			Name:              pulumi.String(fmt.Sprintf("%s-requests", args.Name)),
+			PolicyType:        pulumi.String("TargetTrackingScaling"),
+			ResourceId:        target.ResourceId,
+			ScalableDimension: target.ScalableDimension,
+			ServiceNamespace:  target.ServiceNamespace,
+			TargetTrackingScalingPolicyConfiguration: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationArgs{
+				TargetValue:      pulumi.Float64(float64(autoscaling.RequestsPerTarget)),
+				ScaleInCooldown:  pulumi.Int(scaleInCooldown),
+				ScaleOutCooldown: pulumi.Int(scaleOutCooldown),
+				PredefinedMetricSpecification: &appautoscaling.PolicyTargetTrackingScalingPolicyConfigurationPredefinedMetricSpecificationArgs{
+					PredefinedMetricType: pulumi.String("ALBRequestCountPerTarget"),
+					ResourceLabel: pulumi.All(args.LoadBalancer.LoadBalancerArn, targetGroup.TargetGroupArn).ApplyT(func(arns []interface{}) (string, error) {
+						return requestCountResourceLabel(arns[0].(string), arns[1].(string))
+					}).(pulumi.StringOutput),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// requestCountResourceLabel builds the app/<lb>/<id>/targetgroup/<tg>/<id>
+// label that ALBRequestCountPerTarget is reported under. The ARNs are only
+// known after apply, so the load balancer type is checked here.
+func requestCountResourceLabel(loadBalancerArn string, targetGroupArn string) (string, error) {
+	index := strings.Index(loadBalancerArn, ":loadbalancer/app/")
+	if index < 0 {
+		return "", fmt.Errorf("request count autoscaling needs an application load balancer arn, got %q", loadBalancerArn)
+	}
+	loadBalancer := loadBalancerArn[index+len(":loadbalancer/"):]
+	targetGroup := targetGroupArn[strings.LastIndex(targetGroupArn, ":")+1:]
+
+	return fmt.Sprintf("%s/%s", loadBalancer, targetGroup), nil
+}
+
+func validateAutoscaling(autoscaling *Autoscaling, loadBalancer *LoadBalancer) error {
+	if autoscaling.MinCapacity < 0 || autoscaling.MaxCapacity < 1 || autoscaling.MinCapacity > autoscaling.MaxCapacity {
+		return fmt.Errorf("autoscaling capacity must satisfy 0 <= min <= max and max >= 1, got %d-%d", autoscaling.MinCapacity, autoscaling.MaxCapacity)
+	}
+	if autoscaling.CpuTarget == 0 && autoscaling.RequestsPerTarget == 0 {
+		return fmt.Errorf("autoscaling needs a cpu or request count target")
+	}
+	if autoscaling.CpuTarget < 0 || autoscaling.CpuTarget > 100 {
+		return fmt.Errorf("autoscaling cpu target must be between 1 and 100, got %d", autoscaling.CpuTarget)
+	}
+	if autoscaling.RequestsPerTarget > 0 {
+		if loadBalancer == nil || loadBalancer.LoadBalancerArn == nil {
+			return fmt.Errorf("request count autoscaling needs a load balancer arn")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package ecs
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestRequestCountResourceLabel(t *testing.T) {
+	tests := []struct {
+		name            string
+		loadBalancerArn string
+		targetGroupArn  string
+		want            string
+		wantErr         bool
+	}{
+		{
+			name:            "application load balancer",
+			loadBalancerArn: "arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/web/50dc6c495c0c9188",
+			targetGroupArn:  "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/web/943f017f100becff",
+			want:            "app/web/50dc6c495c0c9188/targetgroup/web/943f017f100becff",
+		},
+		{
+			name:            "network load balancer",
+			loadBalancerArn: "arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/net/web/50dc6c495c0c9188",
+			targetGroupArn:  "arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/web/943f017f100becff",
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := requestCountResourceLabel(tt.loadBalancerArn, tt.targetGroupArn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAutoscaling(t *testing.T) {
+	loadBalancer := &LoadBalancer{LoadBalancerArn: pulumi.String("arn")}
+
+	tests := []struct {
+		name         string
+		autoscaling  Autoscaling
+		loadBalancer *LoadBalancer
+		wantErr      bool
+	}{
+		{name: "cpu", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4, CpuTarget: 60}},
+		{name: "requests", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4, RequestsPerTarget: 100}, loadBalancer: loadBalancer},
+		{name: "min above max", autoscaling: Autoscaling{MinCapacity: 5, MaxCapacity: 4, CpuTarget: 60}, wantErr: true},
+		{name: "no target", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4}, wantErr: true},
+		{name: "cpu above 100", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4, CpuTarget: 101}, wantErr: true},
+		{name: "requests without load balancer", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4, RequestsPerTarget: 100}, wantErr: true},
+		{name: "requests without load balancer arn", autoscaling: Autoscaling{MinCapacity: 1, MaxCapacity: 4, RequestsPerTarget: 100}, loadBalancer: &LoadBalancer{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAutoscaling(&tt.autoscaling, tt.loadBalancer); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
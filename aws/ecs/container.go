@@ -0,0 +1,188 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// _fargateMemory lists, per task CPU unit size, the memory range in MiB and
+// the increment Fargate accepts.
+var _fargateMemory = map[int]struct {
+	Min  int
+	Max  int
+	Step int
+}{
+	256:   {Min: 512, Max: 2048, Step: 512},
+	512:   {Min: 1024, Max: 4096, Step: 1024},
+	1024:  {Min: 2048, Max: 8192, Step: 1024},
+	2048:  {Min: 4096, Max: 16384, Step: 1024},
+	4096:  {Min: 8192, Max: 30720, Step: 1024},
+	8192:  {Min: 16384, Max: 61440, Step: 4096},
+	16384: {Min: 32768, Max: 122880, Step: 8192},
+}
+
+type Container struct {
+	Name  string
+	Image string
+	// Cpu and Memory reserve part of the task's resources; zero leaves them
+	// shared.
+	Cpu    int
+	Memory int
+	// Essential defaults to true.
+	Essential *bool
+	Port      int
+	Command   []string
+	// Environment holds plain variables.
+	Environment map[string]string
+	// Secrets maps variable names to Secrets Manager ARNs, optionally with a
+	// JSON key suffix such as `arn:...:secret:db-AbCdEf:password::`.
+	Secrets map[string]string
+}
+
+type containerDefinition struct {
+	Name             string           `json:"name"`
+	Image            string           `json:"image"`
+	Cpu              int              `json:"cpu,omitempty"`
+	Memory           int              `json:"memory,omitempty"`
+	Essential        bool             `json:"essential"`
+	Command          []string         `json:"command,omitempty"`
+	PortMappings     []portMapping    `json:"portMappings,omitempty"`
+	Environment      []nameValue      `json:"environment,omitempty"`
+	Secrets          []nameValueFrom  `json:"secrets,omitempty"`
+	LogConfiguration logConfiguration `json:"logConfiguration"`
+}
+
+type portMapping struct {
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+type nameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type nameValueFrom struct {
+	Name      string `json:"name"`
+	ValueFrom string `json:"valueFrom"`
+}
+
+type logConfiguration struct {
+	LogDriver string            `json:"logDriver"`
+	Options   map[string]string `json:"options"`
+}
+
+// containerDefinitions renders the task's containers, logging each to its own
+// stream in logGroup.
+func containerDefinitions(containers []*Container, logGroup string, region string) (string, error) {
+	definitions := []containerDefinition{}
+	for _, container := range containers {
+		essential := true
+		if container.Essential != nil {
+			essential = *container.Essential
+		}
+
+		definition := containerDefinition{
+			Name:      container.Name,
+			Image:     container.Image,
+			Cpu:       container.Cpu,
+			Memory:    container.Memory,
+			Essential: essential,
+			Command:   container.Command,
+			LogConfiguration: logConfiguration{
+				LogDriver: "awslogs",
+				Options: map[string]string{
+					"awslogs-group":         logGroup,
+					"awslogs-region":        region,
+					"awslogs-stream-prefix": container.Name,
+				},
+			},
+		}
+		if container.Port != 0 {
+			definition.PortMappings = []portMapping{
+				{
+					ContainerPort: container.Port,
+					Protocol:      "tcp",
+				},
+			}
+		}
+		for _, name := range sortedKeys(container.Environment) {
+			definition.Environment = append(definition.Environment, nameValue{
+				Name:  name,
+				Value: container.Environment[name],
+			})
+		}
+		for _, name := range sortedKeys(container.Secrets) {
+			definition.Secrets = append(definition.Secrets, nameValueFrom{
+				Name:      name,
+				ValueFrom: container.Secrets[name],
+			})
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	document, err := json.Marshal(definitions)
+	if err != nil {
+		return "", err
+	}
+
+	return string(document), nil
+}
+
+func validateContainer(container *Container) error {
+	if container.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if container.Image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if container.Port < 0 || container.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", container.Port)
+	}
+	for name, arn := range container.Secrets {
+		if !strings.HasPrefix(arn, "arn:") || !strings.Contains(arn, ":secretsmanager:") {
+			return fmt.Errorf("secret %s: %q is not a secrets manager arn", name, arn)
+		}
+	}
+
+	return nil
+}
+
+func validateFargateSize(cpu int, memory int) error {
+	size, ok := _fargateMemory[cpu]
+	if !ok {
+		return fmt.Errorf("unsupported fargate cpu %d", cpu)
+	}
+	if memory < size.Min || memory > size.Max || (memory-size.Min)%size.Step != 0 {
+		return fmt.Errorf("fargate cpu %d needs %d-%d MiB of memory in steps of %d, got %d", cpu, size.Min, size.Max, size.Step, memory)
+	}
+	if cpu == 256 && memory == 1536 {
+		return fmt.Errorf("fargate cpu 256 takes 512, 1024 or 2048 MiB of memory, got 1536")
+	}
+
+	return nil
+}
+
+// secretArn strips the JSON key, version stage and version id a secret
+// reference may carry, leaving the ARN IAM policies match on.
+func secretArn(reference string) string {
+	parts := strings.Split(reference, ":")
+	if len(parts) <= 7 {
+		return reference
+	}
+
+	return strings.Join(parts[:7], ":")
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,29 @@
+package ecs
+
+import "testing"
+
+func TestValidateFargateSize(t *testing.T) {
+	tests := []struct {
+		cpu     int
+		memory  int
+		wantErr bool
+	}{
+		{cpu: 256, memory: 512},
+		{cpu: 256, memory: 2048},
+		{cpu: 256, memory: 1536, wantErr: true},
+		{cpu: 256, memory: 4096, wantErr: true},
+		{cpu: 512, memory: 3072},
+		{cpu: 1024, memory: 1024, wantErr: true},
+		{cpu: 4096, memory: 30720},
+		{cpu: 8192, memory: 20480},
+		{cpu: 8192, memory: 18432, wantErr: true},
+		{cpu: 16384, memory: 122880},
+		{cpu: 300, memory: 1024, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validateFargateSize(tt.cpu, tt.memory); (err != nil) != tt.wantErr {
+			t.Errorf("cpu %d memory %d: got error %v, want error %v", tt.cpu, tt.memory, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ecs"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	listenerrule "github.com/tungnt76/pulumi-in-go/aws/elb/alb/listener_rule"
+	targetgroup "github.com/tungnt76/pulumi-in-go/aws/elb/target-group"
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+const _defaultLogRetentionDays = 30
+
+type ServiceArgs struct {
+	Name        string
+	Environment string
+	Tags        map[string]string
+
+	// ClusterArn places the service in an existing cluster. When empty a
+	// cluster named after the service is created.
+	ClusterArn string
+	// Vpc is the output of vpc.CreateVpc; tasks run in its private subnets.
+	Vpc *vpc.VpcOutput
+	// SecurityGroupIds are added next to the service's own security group.
+	SecurityGroupIds []string
+
+	// Cpu is in units (256 = 0.25 vCPU) and Memory in MiB, and must be a
+	// combination Fargate supports.
+	Cpu          int
+	Memory       int
+	Containers   []*Container
+	DesiredCount int
+	// TaskPolicyArns are attached to the task role the containers run as.
+	TaskPolicyArns   []string
+	LogRetentionDays int
+
+	LoadBalancer *LoadBalancer
+	Autoscaling  *Autoscaling
+}
+
+// LoadBalancer registers the service behind an existing ALB listener.
+type LoadBalancer struct {
+	// LoadBalancerArn is needed for request count autoscaling. It and
+	// SecurityGroupId take outputs, so the ALB can be created in the same
+	// program.
+	LoadBalancerArn pulumi.StringInput
+	// SecurityGroupId is the ALB's, which is admitted to the container port.
+	SecurityGroupId pulumi.StringInput
+	ContainerName   string
+	ContainerPort   int
+	// TargetGroup is created with target type ip in Vpc.
+	TargetGroup targetgroup.TargetGroupArgs
+	// Rule forwards to the target group from the listener in
+	// Rule.ListenerArn.
+	Rule listenerrule.ListenerRuleArgs
+	// HealthCheckGracePeriod is in seconds, defaulting to 60.
+	HealthCheckGracePeriod int
+}
+
+type ServiceOutput struct {
+	ClusterArn        pulumi.StringOutput
+	ServiceName       pulumi.StringOutput
+	TaskDefinitionArn pulumi.StringOutput
+	SecurityGroupId   pulumi.IDOutput
+	TaskRoleArn       pulumi.StringOutput
+	ExecutionRoleArn  pulumi.StringOutput
+	TargetGroup       *targetgroup.TargetGroupOutput
+	ListenerRuleArn   pulumi.StringOutput
+}
+
+func CreateService(ctx *pulumi.Context, args *ServiceArgs) (*ServiceOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	if args.DesiredCount == 0 {
+		args.DesiredCount = 1
+	}
+	if args.LogRetentionDays == 0 {
+		args.LogRetentionDays = _defaultLogRetentionDays
+	}
+
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("ecs service %s: %w", args.Name, err)
+	}
+
+	tags := merge(map[string]string{
+		"Name":        args.Name,
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterArn pulumi.StringOutput
+	var clusterName pulumi.StringOutput
+	if args.ClusterArn != "" {
+		clusterArn = pulumi.String(args.ClusterArn).ToStringOutput()
+		clusterName = pulumi.String(args.ClusterArn[strings.LastIndex(args.ClusterArn, "/")+1:]).ToStringOutput()
+	} else {
+		cluster, err := ecs.NewCluster(ctx, fmt.Sprintf("%s-cluster", args.Name), &ecs.ClusterArgs{
+			Name: pulumi.String(args.Name),
+			Settings: ecs.ClusterSettingArray{
+				&ecs.ClusterSettingArgs{
+					Name:  pulumi.String("containerInsights"),
+					Value: pulumi.String("enabled"),
+				},
+			},
+			Tags: pulumi.ToStringMap(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+		clusterArn = cluster.Arn
+		clusterName = cluster.Name
+	}
+
+	executionRole, err := iam.NewRole(ctx, fmt.Sprintf("%s-execution-role", args.Name), &iam.RoleArgs{
+		Name:             pulumi.String(fmt.Sprintf("%s-execution-role", args.Name)),
+		AssumeRolePolicy: pulumi.String(_tasksAssumeRolePolicy),
+		Tags:             pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-execution-role", args.Name), &iam.RolePolicyAttachmentArgs{
+		Role:      executionRole.Name,
+		PolicyArn: pulumi.String(fmt.Sprintf("arn:%s:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy", partition.Partition)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the execution role injects secrets into the containers at start
+	secretArns := []string{}
+	seen := map[string]bool{}
+	for _, container := range args.Containers {
+		for _, name := range sortedKeys(container.Secrets) {
+			arn := secretArn(container.Secrets[name])
+			if !seen[arn] {
+				seen[arn] = true
+				secretArns = append(secretArns, arn)
+			}
+		}
+	}
+	if len(secretArns) > 0 {
+		policy, err := json.Marshal(map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{
+					"Effect":   "Allow",
+					"Action":   []string{"secretsmanager:GetSecretValue"},
+					"Resource": secretArns,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = iam.NewRolePolicy(ctx, fmt.Sprintf("%s-execution-secrets", args.Name), &iam.RolePolicyArgs{
+			Role:   executionRole.Name,
+			Policy: pulumi.String(string(policy)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	taskRole, err := iam.NewRole(ctx, fmt.Sprintf("%s-task-role", args.Name), &iam.RoleArgs{
+		Name:             pulumi.String(fmt.Sprintf("%s-task-role", args.Name)),
+		AssumeRolePolicy: pulumi.String(_tasksAssumeRolePolicy),
+		Tags:             pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for index, policyArn := range args.TaskPolicyArns {
+		_, err = iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-task-role-%d", args.Name, index+1), &iam.RolePolicyAttachmentArgs{
+			Role:      taskRole.Name,
+			PolicyArn: pulumi.String(policyArn),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logGroupName := fmt.Sprintf("/ecs/%s", args.Name)
+	logGroup, err := cloudwatch.NewLogGroup(ctx, fmt.Sprintf("%s-logs", args.Name), &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String(logGroupName),
+		RetentionInDays: pulumi.Int(args.LogRetentionDays),
+		Tags:            pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := containerDefinitions(args.Containers, logGroupName, region.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	taskDefinition, err := ecs.NewTaskDefinition(ctx, fmt.Sprintf("%s-task", args.Name), &ecs.TaskDefinitionArgs{
+		Family:                  pulumi.String(args.Name),
+		Cpu:                     pulumi.String(fmt.Sprintf("%d", args.Cpu)),
+		Memory:                  pulumi.String(fmt.Sprintf("%d", args.Memory)),
+		NetworkMode:             pulumi.String("awsvpc"),
+		RequiresCompatibilities: pulumi.ToStringArray([]string{"FARGATE"}),
+		ExecutionRoleArn:        executionRole.Arn,
+		TaskRoleArn:             taskRole.Arn,
+		ContainerDefinitions:    pulumi.String(definitions),
+		RuntimePlatform: &ecs.TaskDefinitionRuntimePlatformArgs{
+			OperatingSystemFamily: pulumi.String("LINUX"),
+			CpuArchitecture:       pulumi.String("X86_64"),
+		},
+		Tags: pulumi.ToStringMap(tags),
+	}, pulumi.DependsOn([]pulumi.Resource{logGroup}))
+	if err != nil {
+		return nil, err
+	}
+
+	ingress := ec2.SecurityGroupIngressArray{}
+	if args.LoadBalancer != nil && args.LoadBalancer.SecurityGroupId != nil {
+		ingress = append(ingress, ec2.SecurityGroupIngressArgs{
+			Description:    pulumi.String("load balancer"),
+			FromPort:       pulumi.Int(args.LoadBalancer.ContainerPort),
+			ToPort:         pulumi.Int(args.LoadBalancer.ContainerPort),
+			Protocol:       pulumi.String("tcp"),
+			SecurityGroups: pulumi.StringArray{args.LoadBalancer.SecurityGroupId},
+		})
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-ecs-sg", args.Name), &ec2.SecurityGroupArgs{
+		Name:    pulumi.String(fmt.Sprintf("%s-ecs-sg", args.Name)),
+		VpcId:   args.Vpc.VpcId,
+		Ingress: ingress,
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				Protocol:   pulumi.String("-1"),
+				CidrBlocks: pulumi.ToStringArray([]string{"0.0.0.0/0"}),
+			},
+		},
+		Tags: pulumi.ToStringMap(
+			merge(tags, map[string]string{
+				"Name": fmt.Sprintf("%s-ecs-sg", args.Name),
+			}),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	securityGroups := pulumi.StringArray{securityGroup.ID().ToStringOutput()}
+	securityGroups = append(securityGroups, pulumi.ToStringArray(args.SecurityGroupIds)...)
+
+	output := &ServiceOutput{
+		ClusterArn:        clusterArn,
+		TaskDefinitionArn: taskDefinition.Arn,
+		SecurityGroupId:   securityGroup.ID(),
+		TaskRoleArn:       taskRole.Arn,
+		ExecutionRoleArn:  executionRole.Arn,
+	}
+
+	serviceArgs := &ecs.ServiceArgs{
+		Name:           pulumi.String(args.Name),
+		Cluster:        clusterArn,
+		TaskDefinition: taskDefinition.Arn,
+		DesiredCount:   pulumi.Int(args.DesiredCount),
+		LaunchType:     pulumi.String("FARGATE"),
+		NetworkConfiguration: &ecs.ServiceNetworkConfigurationArgs{
+			Subnets:        args.Vpc.PrivateSubnetIds,
+			SecurityGroups: securityGroups,
+			AssignPublicIp: pulumi.Bool(false),
+		},
+		DeploymentCircuitBreaker: &ecs.ServiceDeploymentCircuitBreakerArgs{
+			Enable:   pulumi.Bool(true),
+			Rollback: pulumi.Bool(true),
+		},
+		PropagateTags: pulumi.String("SERVICE"),
+		Tags:          pulumi.ToStringMap(tags),
+	}
+
+	dependsOn := []pulumi.Resource{}
+	if args.LoadBalancer != nil {
+		targetGroupArgs := args.LoadBalancer.TargetGroup
+		if targetGroupArgs.Name == "" {
+			targetGroupArgs.Name = args.Name
+		}
+		targetGroupArgs.VpcId = args.Vpc.VpcId.ToStringOutput()
+		targetGroupArgs.TargetType = "ip"
+		if targetGroupArgs.Port == 0 {
+			targetGroupArgs.Port = args.LoadBalancer.ContainerPort
+		}
+		if targetGroupArgs.Protocol == "" {
+			targetGroupArgs.Protocol = "HTTP"
+		}
+
+		targetGroup, err := targetgroup.CreateTargetGroup(ctx, &targetGroupArgs)
+		if err != nil {
+			return nil, err
+		}
+		output.TargetGroup = targetGroup
+
+		rule := args.LoadBalancer.Rule
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s-rule", args.Name)
+		}
+		rule.TargetGroupArn = targetGroup.TargetGroupArn
+		ruleOutput, err := listenerrule.CreateListenerRule(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+		output.ListenerRuleArn = ruleOutput.ListenerRuleArn
+		dependsOn = append(dependsOn, ruleOutput.ListenerRule)
+
+		healthCheckGracePeriod := args.LoadBalancer.HealthCheckGracePeriod
+		if healthCheckGracePeriod == 0 {
+			healthCheckGracePeriod = 60
+		}
+
+		serviceArgs.LoadBalancers = ecs.ServiceLoadBalancerArray{
+			&ecs.ServiceLoadBalancerArgs{
+				TargetGroupArn: targetGroup.TargetGroupArn,
+				ContainerName:  pulumi.String(args.LoadBalancer.ContainerName),
+				ContainerPort:  pulumi.Int(args.LoadBalancer.ContainerPort),
+			},
+		}
+		serviceArgs.HealthCheckGracePeriodSeconds = pulumi.Int(healthCheckGracePeriod)
+	}
+
+	// ECS rejects target groups that are not attached to a load balancer yet,
+	// so the service waits for the listener rule
+	opts := []pulumi.ResourceOption{pulumi.DependsOn(dependsOn)}
+	if args.Autoscaling != nil {
+		// the desired count is owned by the scaling policies once they exist
+		opts = append(opts, pulumi.IgnoreChanges([]string{"desiredCount"}))
+	}
+
+	service, err := ecs.NewService(ctx, fmt.Sprintf("%s-service", args.Name), serviceArgs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	output.ServiceName = service.Name
+
+	if args.Autoscaling != nil {
+		err = createAutoscaling(ctx, args, clusterName, service.Name, output.TargetGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+const _tasksAssumeRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "Service": "ecs-tasks.amazonaws.com"
+      },
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`
+
+func validate(args *ServiceArgs) error {
+	if args.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if args.Vpc == nil {
+		return fmt.Errorf("vpc cannot be nil")
+	}
+	if len(args.Containers) == 0 {
+		return fmt.Errorf("at least one container is required")
+	}
+	if err := validateFargateSize(args.Cpu, args.Memory); err != nil {
+		return err
+	}
+
+	names := map[string]*Container{}
+	cpu, memory := 0, 0
+	for _, container := range args.Containers {
+		if err := validateContainer(container); err != nil {
+			return fmt.Errorf("container %s: %w", container.Name, err)
+		}
+		if names[container.Name] != nil {
+			return fmt.Errorf("container %s is defined more than once", container.Name)
+		}
+		names[container.Name] = container
+		cpu += container.Cpu
+		memory += container.Memory
+	}
+	if cpu > args.Cpu || memory > args.Memory {
+		return fmt.Errorf("containers reserve %d cpu and %d MiB, more than the task's %d cpu and %d MiB", cpu, memory, args.Cpu, args.Memory)
+	}
+
+	if args.LoadBalancer != nil {
+		container := names[args.LoadBalancer.ContainerName]
+		if container == nil {
+			return fmt.Errorf("load balancer container %q is not defined", args.LoadBalancer.ContainerName)
+		}
+		if container.Port != args.LoadBalancer.ContainerPort {
+			return fmt.Errorf("container %s does not expose port %d", container.Name, args.LoadBalancer.ContainerPort)
+		}
+		if args.LoadBalancer.Rule.ListenerArn == nil {
+			return fmt.Errorf("load balancer rule needs a listener arn")
+		}
+	}
+
+	if args.Autoscaling != nil {
+		if err := validateAutoscaling(args.Autoscaling, args.LoadBalancer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
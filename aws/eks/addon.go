@@ -0,0 +1,264 @@
+package eks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+const (
+	// VersionLatest picks the most recent add-on version compatible with the
+	// cluster; an empty version picks the default one.
+	VersionLatest = "latest"
+
+	_defaultResolveConflicts = "OVERWRITE"
+)
+
+var _resolveConflictsModes = []string{"NONE", "OVERWRITE", "PRESERVE"}
+
+// _defaultAddons make a fresh cluster usable.
+var _defaultAddons = []string{"vpc-cni", "kube-proxy", "coredns", "eks-pod-identity-agent", "aws-ebs-csi-driver"}
+
+// _addonsNeedingNodes run as deployments and only become healthy once nodes
+// have joined.
+var _addonsNeedingNodes = []string{"coredns", "aws-ebs-csi-driver"}
+
+// _addonServiceAccounts are the service accounts and AWS managed policies of
+// add-ons that call AWS APIs.
+var _addonServiceAccounts = map[string]struct {
+	ServiceAccount string
+	PolicyArns     []string
+}{
+	"vpc-cni": {
+		ServiceAccount: "aws-node",
+		PolicyArns:     []string{"AmazonEKS_CNI_Policy"},
+	},
+	"aws-ebs-csi-driver": {
+		ServiceAccount: "ebs-csi-controller-sa",
+		PolicyArns:     []string{"service-role/AmazonEBSCSIDriverPolicy"},
+	},
+}
+
+type Addon struct {
+	Name string `json:"name"`
+	// Version pins the add-on; see VersionLatest.
+	Version string `json:"version"`
+	// ConfigurationValues must match the add-on's configuration schema.
+	ConfigurationValues json.RawMessage `json:"configuration_values"`
+	// ServiceAccount and PolicyArns give the add-on an IRSA role. Known
+	// add-ons such as vpc-cni and aws-ebs-csi-driver get one by default.
+	ServiceAccount string   `json:"service_account"`
+	PolicyArns     []string `json:"policy_arns"`
+	// ResolveConflicts is NONE, OVERWRITE or PRESERVE, defaulting to
+	// OVERWRITE. EKS only accepts PRESERVE on updates, so installs use
+	// OVERWRITE instead.
+	ResolveConflicts string `json:"resolve_conflicts"`
+}
+
+type AddonsArgs struct {
+	Cluster     *ClusterOutput
+	Environment string
+	Tags        map[string]string
+	// Addons defaults to vpc-cni, kube-proxy, coredns, eks-pod-identity-agent
+	// and aws-ebs-csi-driver.
+	Addons []*Addon
+	// OidcProvider is created when nil and an add-on needs a role.
+	OidcProvider *OidcProviderOutput
+	// Nodes must exist before add-ons that run as deployments are installed,
+	// see NodeGroupsOutput.Resources.
+	Nodes []pulumi.Resource
+}
+
+type AddonOutput struct {
+	AddonArn     pulumi.StringOutput
+	AddonVersion pulumi.StringOutput
+	RoleArn      pulumi.StringOutput
+}
+
+type AddonsOutput struct {
+	Addons map[string]*AddonOutput
+	// Resources are the add-ons, for workloads that need them installed.
+	Resources []pulumi.Resource
+}
+
+// AddonsFromConfig reads the add-ons under `<namespace>:addons`, returning nil
+// so the defaults apply when none are configured.
+func AddonsFromConfig(ctx *pulumi.Context, namespace string) ([]*Addon, error) {
+	var addons []*Addon
+	if err := config.New(ctx, namespace).GetObject("addons", &addons); err != nil {
+		return nil, err
+	}
+
+	return addons, nil
+}
+
+func CreateAddons(ctx *pulumi.Context, args *AddonsArgs) (*AddonsOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	configured := args.Addons
+	if configured == nil {
+		for _, name := range _defaultAddons {
+			configured = append(configured, &Addon{
+				Name: name,
+			})
+		}
+	}
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// defaults are set on copies so the caller's add-ons are left untouched
+	addons := []*Addon{}
+	names := map[string]bool{}
+	serviceAccounts := []*ServiceAccount{}
+	for _, addon := range configured {
+		addon := *addon
+		addon.PolicyArns = append([]string{}, addon.PolicyArns...)
+		setAddonDefaults(&addon, partition.Partition)
+		if err := validateAddon(&addon); err != nil {
+			return nil, fmt.Errorf("addon %s: %w", addon.Name, err)
+		}
+		if names[addon.Name] {
+			return nil, fmt.Errorf("addon %s is defined more than once", addon.Name)
+		}
+		names[addon.Name] = true
+		addons = append(addons, &addon)
+
+		if addon.ServiceAccount != "" {
+			serviceAccounts = append(serviceAccounts, &ServiceAccount{
+				Name:           addon.Name,
+				Namespace:      "kube-system",
+				ServiceAccount: addon.ServiceAccount,
+				// add-ons such as vpc-cni need credentials before the pod
+				// identity agent is running
+				Mode:       ModeIrsa,
+				PolicyArns: addon.PolicyArns,
+			})
+		}
+	}
+
+	roles, err := CreateServiceAccountRoles(ctx, &ServiceAccountsArgs{
+		Cluster:         args.Cluster,
+		Environment:     args.Environment,
+		Tags:            args.Tags,
+		OidcProvider:    args.OidcProvider,
+		ServiceAccounts: serviceAccounts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := merge(map[string]string{
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	output := &AddonsOutput{
+		Addons: map[string]*AddonOutput{},
+	}
+
+	for _, addon := range addons {
+		addonArgs := &eks.AddonArgs{
+			ClusterName:              args.Cluster.ClusterName,
+			AddonName:                pulumi.String(addon.Name),
+			AddonVersion:             addonVersion(ctx, args.Cluster, addon),
+			ResolveConflictsOnCreate: pulumi.String(resolveConflictsOnCreate(addon.ResolveConflicts)),
+			ResolveConflictsOnUpdate: pulumi.String(addon.ResolveConflicts),
+			Tags:                     pulumi.ToStringMap(tags),
+		}
+		if len(addon.ConfigurationValues) > 0 {
+			addonArgs.ConfigurationValues = pulumi.String(string(addon.ConfigurationValues))
+		}
+
+		addonOutput := &AddonOutput{}
+		if role, ok := roles.Roles[addon.Name]; ok {
+			addonArgs.ServiceAccountRoleArn = role.RoleArn
+			addonOutput.RoleArn = role.RoleArn
+		}
+
+		opts := []pulumi.ResourceOption{}
+		if contains(_addonsNeedingNodes, addon.Name) {
+			opts = append(opts, pulumi.DependsOn(args.Nodes))
+		}
+
+		eksAddon, err := eks.NewAddon(ctx, fmt.Sprintf("%s-%s", args.Cluster.Name, addon.Name), addonArgs, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		addonOutput.AddonArn = eksAddon.Arn
+		addonOutput.AddonVersion = eksAddon.AddonVersion
+		output.Addons[addon.Name] = addonOutput
+		output.Resources = append(output.Resources, eksAddon)
+	}
+
+	return output, nil
+}
+
+// addonVersion resolves an unpinned version against the cluster's Kubernetes
+// version.
+func addonVersion(ctx *pulumi.Context, cluster *ClusterOutput, addon *Addon) pulumi.StringInput {
+	if addon.Version != "" && addon.Version != VersionLatest {
+		return pulumi.String(addon.Version)
+	}
+
+	return eks.GetAddonVersionOutput(ctx, eks.GetAddonVersionOutputArgs{
+		AddonName:         pulumi.String(addon.Name),
+		KubernetesVersion: cluster.Version,
+		MostRecent:        pulumi.Bool(addon.Version == VersionLatest),
+	}).Version()
+}
+
+// resolveConflictsOnCreate maps PRESERVE, which EKS rejects when creating an
+// add-on, to OVERWRITE; there is nothing to preserve on a fresh install.
+func resolveConflictsOnCreate(mode string) string {
+	if mode == "PRESERVE" {
+		return "OVERWRITE"
+	}
+
+	return mode
+}
+
+func setAddonDefaults(addon *Addon, partition string) {
+	if addon.ResolveConflicts == "" {
+		addon.ResolveConflicts = _defaultResolveConflicts
+	}
+
+	serviceAccount, ok := _addonServiceAccounts[addon.Name]
+	if !ok || addon.ServiceAccount != "" {
+		return
+	}
+	addon.ServiceAccount = serviceAccount.ServiceAccount
+	if len(addon.PolicyArns) == 0 {
+		for _, policy := range serviceAccount.PolicyArns {
+			addon.PolicyArns = append(addon.PolicyArns, fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition, policy))
+		}
+	}
+}
+
+func validateAddon(addon *Addon) error {
+	if addon.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if !contains(_resolveConflictsModes, addon.ResolveConflicts) {
+		return fmt.Errorf("unsupported resolve conflicts mode %q", addon.ResolveConflicts)
+	}
+	if len(addon.ConfigurationValues) > 0 && !json.Valid(addon.ConfigurationValues) {
+		return fmt.Errorf("configuration values are not valid json")
+	}
+	if addon.ServiceAccount != "" && len(addon.PolicyArns) == 0 {
+		return fmt.Errorf("service account %s needs at least one policy arn", addon.ServiceAccount)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package eks
+
+import "testing"
+
+func TestResolveConflictsOnCreate(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: "NONE", want: "NONE"},
+		{mode: "OVERWRITE", want: "OVERWRITE"},
+		{mode: "PRESERVE", want: "OVERWRITE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if got := resolveConflictsOnCreate(tt.mode); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAddonDefaults(t *testing.T) {
+	tests := []struct {
+		name               string
+		addon              Addon
+		wantServiceAccount string
+		wantPolicyArns     []string
+		wantResolve        string
+	}{
+		{
+			name:        "plain add-on",
+			addon:       Addon{Name: "coredns"},
+			wantResolve: "OVERWRITE",
+		},
+		{
+			name:               "known add-on gets its role",
+			addon:              Addon{Name: "vpc-cni", ResolveConflicts: "PRESERVE"},
+			wantServiceAccount: "aws-node",
+			wantPolicyArns:     []string{"arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy"},
+			wantResolve:        "PRESERVE",
+		},
+		{
+			name:               "explicit service account is kept",
+			addon:              Addon{Name: "vpc-cni", ServiceAccount: "cni", PolicyArns: []string{"arn:aws:iam::123456789012:policy/cni"}},
+			wantServiceAccount: "cni",
+			wantPolicyArns:     []string{"arn:aws:iam::123456789012:policy/cni"},
+			wantResolve:        "OVERWRITE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addon := tt.addon
+			setAddonDefaults(&addon, "aws")
+			if addon.ServiceAccount != tt.wantServiceAccount {
+				t.Errorf("got service account %q, want %q", addon.ServiceAccount, tt.wantServiceAccount)
+			}
+			if len(addon.PolicyArns) != len(tt.wantPolicyArns) {
+				t.Fatalf("got policy arns %v, want %v", addon.PolicyArns, tt.wantPolicyArns)
+			}
+			for i := range addon.PolicyArns {
+				if addon.PolicyArns[i] != tt.wantPolicyArns[i] {
+					t.Errorf("got policy arns %v, want %v", addon.PolicyArns, tt.wantPolicyArns)
+				}
+			}
+			if addon.ResolveConflicts != tt.wantResolve {
+				t.Errorf("got resolve conflicts %q, want %q", addon.ResolveConflicts, tt.wantResolve)
+			}
+			if err := validateAddon(&addon); err != nil {
+				t.Errorf("unexpected validation error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAddon(t *testing.T) {
+	tests := []struct {
+		name    string
+		addon   Addon
+		wantErr bool
+	}{
+		{name: "valid", addon: Addon{Name: "coredns", ResolveConflicts: "NONE"}},
+		{name: "no name", addon: Addon{ResolveConflicts: "NONE"}, wantErr: true},
+		{name: "unknown mode", addon: Addon{Name: "coredns", ResolveConflicts: "MERGE"}, wantErr: true},
+		{name: "invalid configuration", addon: Addon{Name: "coredns", ResolveConflicts: "NONE", ConfigurationValues: []byte("{")}, wantErr: true},
+		{name: "service account without policy", addon: Addon{Name: "x", ResolveConflicts: "NONE", ServiceAccount: "x"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAddon(&tt.addon); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
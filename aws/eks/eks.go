@@ -0,0 +1,424 @@
+package eks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+const (
+	EndpointPrivate          = "private"
+	EndpointPublic           = "public"
+	EndpointPublicAndPrivate = "public_and_private"
+
+	_defaultLogRetentionDays = 90
+)
+
+var _endpointAccessModes = []string{EndpointPrivate, EndpointPublic, EndpointPublicAndPrivate}
+
+var _logTypes = []string{"api", "audit", "authenticator", "controllerManager", "scheduler"}
+
+var _defaultLogTypes = []string{"api", "audit", "authenticator"}
+
+type ClusterArgs struct {
+	Name        string
+	Version     string
+	Environment string
+	Tags        map[string]string
+
+	// Vpc is the output of vpc.CreateVpc. The control plane is placed in
+	// its private subnets.
+	Vpc *vpc.VpcOutput
+
+	// EndpointAccess is one of private, public or public_and_private,
+	// defaulting to public_and_private.
+	EndpointAccess string
+	// PublicAccessCidrs limits who can reach the public endpoint.
+	PublicAccessCidrs []string
+	// PrivateAccessCidrs are admitted to the private endpoint on 443, e.g.
+	// the VPC CIDR or a VPN range.
+	PrivateAccessCidrs []string
+
+	// LogTypes are the control plane logs sent to CloudWatch, defaulting to
+	// api, audit and authenticator.
+	LogTypes         []string
+	LogRetentionDays int
+
+	// KmsKeyArn encrypts Kubernetes secrets. When empty a key is created.
+	KmsKeyArn       string
+	ServiceIpv4Cidr string
+}
+
+type ClusterOutput struct {
+	// Name is the plain cluster name, for logical names of resources that
+	// belong to the cluster.
+	Name        string
+	ClusterName pulumi.StringOutput
+	ClusterArn  pulumi.StringOutput
+	Version     pulumi.StringOutput
+	Endpoint    pulumi.StringOutput
+	// CertificateAuthority is the base64 encoded cluster CA.
+	CertificateAuthority pulumi.StringOutput
+	// SecurityGroupId is the additional control plane security group;
+	// ClusterSecurityGroupId is the one EKS creates for the cluster.
+	SecurityGroupId        pulumi.IDOutput
+	ClusterSecurityGroupId pulumi.StringOutput
+	OidcIssuerUrl          pulumi.StringOutput
+	RoleArn                pulumi.StringOutput
+	KmsKeyArn              pulumi.StringOutput
+	// Kubeconfig authenticates with `aws eks get-token` and is a secret.
+	Kubeconfig pulumi.StringOutput
+	Vpc        *vpc.VpcOutput
+}
+
+func CreateCluster(ctx *pulumi.Context, args *ClusterArgs) (*ClusterOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	if args.EndpointAccess == "" {
+		args.EndpointAccess = EndpointPublicAndPrivate
+	}
+	if args.LogTypes == nil {
+		args.LogTypes = _defaultLogTypes
+	}
+	if args.LogRetentionDays == 0 {
+		args.LogRetentionDays = _defaultLogRetentionDays
+	}
+
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("eks cluster %s: %w", args.Name, err)
+	}
+
+	tags := merge(map[string]string{
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := iam.NewRole(ctx, fmt.Sprintf("%s-cluster-role", args.Name), &iam.RoleArgs{
+		Name:             pulumi.String(fmt.Sprintf("%s-cluster-role", args.Name)),
+		AssumeRolePolicy: pulumi.String(assumeRolePolicy("eks.amazonaws.com")),
+		Tags:             pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []pulumi.Resource{}
+	for _, policy := range []string{"AmazonEKSClusterPolicy", "AmazonEKSVPCResourceController"} {
+		attachment, err := iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-cluster-role-%s", args.Name, policy), &iam.RolePolicyAttachmentArgs{
+			Role:      role.Name,
+			PolicyArn: pulumi.String(fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition.Partition, policy)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	var kmsKeyArn pulumi.StringOutput
+	if args.KmsKeyArn != "" {
+		kmsKeyArn = pulumi.String(args.KmsKeyArn).ToStringOutput()
+	} else {
+		key, err := kms.NewKey(ctx, fmt.Sprintf("%s-eks-secrets", args.Name), &kms.KeyArgs{
+			Description:       pulumi.String(fmt.Sprintf("EKS secrets encryption for %s", args.Name)),
+			EnableKeyRotation: pulumi.Bool(true),
+			Tags:              pulumi.ToStringMap(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = kms.NewAlias(ctx, fmt.Sprintf("%s-eks-secrets", args.Name), &kms.AliasArgs{
+			Name:        pulumi.String(fmt.Sprintf("alias/eks/%s", args.Name)),
+			TargetKeyId: key.KeyId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		kmsKeyArn = key.Arn
+	}
+
+	encryptionPolicy, err := iam.NewRolePolicy(ctx, fmt.Sprintf("%s-cluster-encryption", args.Name), &iam.RolePolicyArgs{
+		Role: role.Name,
+		Policy: kmsKeyArn.ApplyT(func(arn string) (string, error) {
+			return policyDocument([]statement{
+				{
+					Effect:   "Allow",
+					Action:   []string{"kms:Encrypt", "kms:Decrypt", "kms:ListGrants", "kms:DescribeKey"},
+					Resource: []string{arn},
+				},
+			})
+		}).(pulumi.StringOutput),
+	})
+	if err != nil {
+		return nil, err
+	}
+	attachments = append(attachments, encryptionPolicy)
+
+	ingress := ec2.SecurityGroupIngressArray{}
+	if len(args.PrivateAccessCidrs) > 0 {
+		ingress = append(ingress, ec2.SecurityGroupIngressArgs{
+			Description: pulumi.String("private endpoint"),
+			FromPort:    pulumi.Int(443),
+			ToPort:      pulumi.Int(443),
+			Protocol:    pulumi.String("tcp"),
+			CidrBlocks:  pulumi.ToStringArray(args.PrivateAccessCidrs),
+		})
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-cluster-sg", args.Name), &ec2.SecurityGroupArgs{
+		Name:        pulumi.String(fmt.Sprintf("%s-cluster-sg", args.Name)),
+		Description: pulumi.String(fmt.Sprintf("EKS control plane for %s", args.Name)),
+		VpcId:       args.Vpc.VpcId.ToStringOutput(),
+		Ingress:     ingress,
+		Egress: ec2.SecurityGroupEgressArray{
+			ec2.SecurityGroupEgressArgs{
+				FromPort:   pulumi.Int(0),
+				ToPort:     pulumi.Int(0),
+				Protocol:   pulumi.String("-1"),
+				CidrBlocks: pulumi.ToStringArray([]string{"0.0.0.0/0"}),
+			},
+		},
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name": fmt.Sprintf("%s-cluster-sg", args.Name),
+			}, tags),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the log group is created up front so its retention applies, EKS would
+	// otherwise create one that never expires
+	logGroup, err := cloudwatch.NewLogGroup(ctx, fmt.Sprintf("%s-cluster-logs", args.Name), &cloudwatch.LogGroupArgs{
+		Name:            pulumi.String(fmt.Sprintf("/aws/eks/%s/cluster", args.Name)),
+		RetentionInDays: pulumi.Int(args.LogRetentionDays),
+		Tags:            pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+	attachments = append(attachments, logGroup)
+
+	vpcConfig := &eks.ClusterVpcConfigArgs{
+		SubnetIds:             args.Vpc.PrivateSubnetIds,
+		SecurityGroupIds:      pulumi.StringArray{securityGroup.ID().ToStringOutput()},
+		EndpointPrivateAccess: pulumi.Bool(args.EndpointAccess != EndpointPublic),
+		EndpointPublicAccess:  pulumi.Bool(args.EndpointAccess != EndpointPrivate),
+	}
+	if len(args.PublicAccessCidrs) > 0 {
+		vpcConfig.PublicAccessCidrs = pulumi.ToStringArray(args.PublicAccessCidrs)
+	}
+
+	clusterArgs := &eks.ClusterArgs{
+		Name:                   pulumi.String(args.Name),
+		RoleArn:                role.Arn,
+		VpcConfig:              vpcConfig,
+		EnabledClusterLogTypes: pulumi.ToStringArray(args.LogTypes),
+		EncryptionConfig: &eks.ClusterEncryptionConfigArgs{
+			Provider: &eks.ClusterEncryptionConfigProviderArgs{
+				KeyArn: kmsKeyArn,
+			},
+			Resources: pulumi.ToStringArray([]string{"secrets"}),
+		},
+		AccessConfig: &eks.ClusterAccessConfigArgs{
+			AuthenticationMode:                      pulumi.String("API_AND_CONFIG_MAP"),
+			BootstrapClusterCreatorAdminPermissions: pulumi.Bool(true),
+		},
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name": args.Name,
+			}, tags),
+		),
+	}
+	if args.Version != "" {
+		clusterArgs.Version = pulumi.String(args.Version)
+	}
+	if args.ServiceIpv4Cidr != "" {
+		clusterArgs.KubernetesNetworkConfig = &eks.ClusterKubernetesNetworkConfigArgs{
+			ServiceIpv4Cidr: pulumi.String(args.ServiceIpv4Cidr),
+		}
+	}
+
+	cluster, err := eks.NewCluster(ctx, args.Name, clusterArgs, pulumi.DependsOn(attachments))
+	if err != nil {
+		return nil, err
+	}
+
+	// nodes launched by Karpenter join the security group EKS created for the
+	// cluster, found by its discovery tag
+	clusterSecurityGroupId := cluster.VpcConfig.ClusterSecurityGroupId().Elem()
+	_, err = ec2.NewTag(ctx, fmt.Sprintf("%s-cluster-sg-discovery", args.Name), &ec2.TagArgs{
+		ResourceId: clusterSecurityGroupId,
+		Key:        pulumi.String(vpc.KarpenterDiscoveryTag),
+		Value:      pulumi.String(args.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	certificateAuthority := cluster.CertificateAuthority.Data().Elem()
+	kubeconfig := pulumi.ToSecret(
+		pulumi.All(cluster.Name, cluster.Endpoint, certificateAuthority).ApplyT(func(values []interface{}) string {
+			return renderKubeconfig(values[0].(string), values[1].(string), values[2].(string))
+		}),
+	).(pulumi.StringOutput)
+	ctx.Export(fmt.Sprintf("%s-kubeconfig", args.Name), kubeconfig)
+
+	return &ClusterOutput{
+		Name:                   args.Name,
+		ClusterName:            cluster.Name,
+		ClusterArn:             cluster.Arn,
+		Version:                cluster.Version,
+		Endpoint:               cluster.Endpoint,
+		CertificateAuthority:   certificateAuthority,
+		SecurityGroupId:        securityGroup.ID(),
+		ClusterSecurityGroupId: clusterSecurityGroupId,
+		OidcIssuerUrl:          oidcIssuerUrl(cluster),
+		RoleArn:                role.Arn,
+		KmsKeyArn:              kmsKeyArn,
+		Kubeconfig:             kubeconfig,
+		Vpc:                    args.Vpc,
+	}, nil
+}
+
+func validate(args *ClusterArgs) error {
+	if args.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if args.Vpc == nil {
+		return fmt.Errorf("vpc cannot be nil")
+	}
+	if !contains(_endpointAccessModes, args.EndpointAccess) {
+		return fmt.Errorf("unsupported endpoint access %q", args.EndpointAccess)
+	}
+	if args.EndpointAccess == EndpointPrivate && len(args.PublicAccessCidrs) > 0 {
+		return fmt.Errorf("public access cidrs need a public endpoint")
+	}
+	for _, logType := range args.LogTypes {
+		if !contains(_logTypes, logType) {
+			return fmt.Errorf("unsupported log type %q", logType)
+		}
+	}
+
+	return nil
+}
+
+// oidcIssuerUrl returns the cluster's OIDC issuer, which IRSA trust policies
+// are written against.
+func oidcIssuerUrl(cluster *eks.Cluster) pulumi.StringOutput {
+	return cluster.Identities.ApplyT(func(identities []eks.ClusterIdentity) string {
+		for _, identity := range identities {
+			for _, oidc := range identity.Oidcs {
+				if oidc.Issuer != nil {
+					return *oidc.Issuer
+				}
+			}
+		}
+
+		return ""
+	}).(pulumi.StringOutput)
+}
+
+const _kubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: %[2]s
+    certificate-authority-data: %[3]s
+  name: %[1]s
+contexts:
+- context:
+    cluster: %[1]s
+    user: %[1]s
+  name: %[1]s
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args:
+      - eks
+      - get-token
+      - --cluster-name
+      - %[1]s
+`
+
+func renderKubeconfig(name string, endpoint string, certificateAuthority string) string {
+	return fmt.Sprintf(_kubeconfigTemplate, name, endpoint, certificateAuthority)
+}
+
+type statement struct {
+	Effect    string                 `json:"Effect"`
+	Principal map[string]interface{} `json:"Principal,omitempty"`
+	Action    []string               `json:"Action"`
+	Resource  []string               `json:"Resource,omitempty"`
+	// Condition values are a string or a list of strings.
+	Condition map[string]map[string]interface{} `json:"Condition,omitempty"`
+}
+
+func policyDocument(statements []statement) (string, error) {
+	document, err := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return string(document), nil
+}
+
+// assumeRolePolicy lets an AWS service assume the role.
+func assumeRolePolicy(service string) string {
+	document, _ := policyDocument([]statement{
+		{
+			Effect: "Allow",
+			Principal: map[string]interface{}{
+				"Service": service,
+			},
+			Action: []string{"sts:AssumeRole"},
+		},
+	})
+
+	return document
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
@@ -0,0 +1,692 @@
+package eks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/cloudwatch"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/sqs"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+const (
+	_karpenterChart          = "oci://public.ecr.aws/karpenter/karpenter"
+	_karpenterChartVersion   = "1.0.6"
+	_karpenterServiceAccount = "karpenter"
+	_karpenterNodePoolTag    = "karpenter.sh/nodepool"
+)
+
+var _kubernetesTaintEffects = []string{"NoSchedule", "NoExecute", "PreferNoSchedule"}
+
+var _consolidationPolicies = []string{"WhenEmpty", "WhenEmptyOrUnderutilized"}
+
+// _interruptionEvents are forwarded to the interruption queue so Karpenter can
+// drain nodes before EC2 reclaims them.
+var _interruptionEvents = []struct {
+	Name       string
+	Source     string
+	DetailType string
+}{
+	{Name: "health", Source: "aws.health", DetailType: "AWS Health Event"},
+	{Name: "spot-interruption", Source: "aws.ec2", DetailType: "EC2 Spot Instance Interruption Warning"},
+	{Name: "rebalance", Source: "aws.ec2", DetailType: "EC2 Instance Rebalance Recommendation"},
+	{Name: "state-change", Source: "aws.ec2", DetailType: "EC2 Instance State-change Notification"},
+}
+
+// NodeClass renders an EC2NodeClass that finds subnets and security groups by
+// the cluster's discovery tag.
+type NodeClass struct {
+	Name string `json:"name"`
+	// AmiAlias defaults to al2023@latest.
+	AmiAlias string `json:"ami_alias"`
+	// VolumeSize is the root volume size in GiB, defaulting to 20.
+	VolumeSize int               `json:"volume_size"`
+	Tags       map[string]string `json:"tags"`
+}
+
+type NodePool struct {
+	Name string `json:"name"`
+	// NodeClass defaults to default.
+	NodeClass string `json:"node_class"`
+	// InstanceCategories defaults to c, m and r.
+	InstanceCategories []string `json:"instance_categories"`
+	// CapacityTypes are on-demand and/or spot, defaulting to on-demand.
+	CapacityTypes []string `json:"capacity_types"`
+	// Architectures defaults to amd64.
+	Architectures []string          `json:"architectures"`
+	Labels        map[string]string `json:"labels"`
+	// Taints use the Kubernetes effects NoSchedule, NoExecute and
+	// PreferNoSchedule.
+	Taints []*Taint `json:"taints"`
+	// CpuLimit caps the vCPUs the pool may launch; zero means unlimited.
+	CpuLimit int `json:"cpu_limit"`
+	// ConsolidationPolicy defaults to WhenEmptyOrUnderutilized.
+	ConsolidationPolicy string `json:"consolidation_policy"`
+	ConsolidateAfter    string `json:"consolidate_after"`
+	ExpireAfter         string `json:"expire_after"`
+}
+
+type KarpenterArgs struct {
+	Cluster     *ClusterOutput
+	Environment string
+	Tags        map[string]string
+	// Namespace defaults to kube-system.
+	Namespace    string
+	ChartVersion string
+	// Provider is created from the cluster kubeconfig when nil.
+	Provider *kubernetes.Provider
+	// DependsOn are resources the controller needs first, e.g. the managed
+	// node groups it runs on and the pod identity agent add-on.
+	DependsOn []pulumi.Resource
+	// NodeClasses and NodePools default to a single default of each.
+	NodeClasses []*NodeClass
+	NodePools   []*NodePool
+}
+
+type KarpenterOutput struct {
+	ControllerRoleArn   pulumi.StringOutput
+	NodeRoleArn         pulumi.StringOutput
+	NodeRoleName        pulumi.StringOutput
+	InstanceProfileName pulumi.StringOutput
+	QueueArn            pulumi.StringOutput
+	QueueUrl            pulumi.StringOutput
+	Release             *helmv3.Release
+	Resources           []pulumi.Resource
+}
+
+// NodeClassesFromConfig reads the node classes under `<namespace>:node_classes`.
+func NodeClassesFromConfig(ctx *pulumi.Context, namespace string) ([]*NodeClass, error) {
+	nodeClasses := []*NodeClass{}
+	if err := config.New(ctx, namespace).GetObject("node_classes", &nodeClasses); err != nil {
+		return nil, err
+	}
+
+	return nodeClasses, nil
+}
+
+// NodePoolsFromConfig reads the node pools under `<namespace>:node_pools`.
+func NodePoolsFromConfig(ctx *pulumi.Context, namespace string) ([]*NodePool, error) {
+	nodePools := []*NodePool{}
+	if err := config.New(ctx, namespace).GetObject("node_pools", &nodePools); err != nil {
+		return nil, err
+	}
+
+	return nodePools, nil
+}
+
+func CreateKarpenter(ctx *pulumi.Context, args *KarpenterArgs) (*KarpenterOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	if args.Namespace == "" {
+		args.Namespace = "kube-system"
+	}
+	if args.ChartVersion == "" {
+		args.ChartVersion = _karpenterChartVersion
+	}
+	if len(args.NodeClasses) == 0 {
+		args.NodeClasses = []*NodeClass{{Name: "default"}}
+	}
+	if len(args.NodePools) == 0 {
+		args.NodePools = []*NodePool{{Name: "default"}}
+	}
+
+	nodeClasses := map[string]bool{}
+	for _, nodeClass := range args.NodeClasses {
+		setNodeClassDefaults(nodeClass)
+		if nodeClass.Name == "" {
+			return nil, fmt.Errorf("node class name cannot be empty")
+		}
+		if nodeClasses[nodeClass.Name] {
+			return nil, fmt.Errorf("node class %s is defined more than once", nodeClass.Name)
+		}
+		nodeClasses[nodeClass.Name] = true
+	}
+
+	nodePools := map[string]bool{}
+	for _, nodePool := range args.NodePools {
+		setNodePoolDefaults(nodePool)
+		if err := validateNodePool(nodePool, nodeClasses); err != nil {
+			return nil, fmt.Errorf("node pool %s: %w", nodePool.Name, err)
+		}
+		if nodePools[nodePool.Name] {
+			return nil, fmt.Errorf("node pool %s is defined more than once", nodePool.Name)
+		}
+		nodePools[nodePool.Name] = true
+	}
+
+	clusterName := args.Cluster.Name
+	tags := merge(map[string]string{
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := aws.GetCallerIdentity(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeRoleName := fmt.Sprintf("%s-karpenter-node", clusterName)
+	nodeRole, err := iam.NewRole(ctx, nodeRoleName, &iam.RoleArgs{
+		Name:             pulumi.String(nodeRoleName),
+		AssumeRolePolicy: pulumi.String(assumeRolePolicy("ec2.amazonaws.com")),
+		Tags:             pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range _nodePolicies {
+		_, err = iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-%s", nodeRoleName, policy), &iam.RolePolicyAttachmentArgs{
+			Role:      nodeRole.Name,
+			PolicyArn: pulumi.String(fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition.Partition, policy)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	instanceProfile, err := iam.NewInstanceProfile(ctx, nodeRoleName, &iam.InstanceProfileArgs{
+		Name: pulumi.String(nodeRoleName),
+		Role: nodeRole.Name,
+		Tags: pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// nodes join the cluster through an access entry rather than aws-auth
+	accessEntry, err := eks.NewAccessEntry(ctx, nodeRoleName, &eks.AccessEntryArgs{
+		ClusterName:  args.Cluster.ClusterName,
+		PrincipalArn: nodeRole.Arn,
+		Type:         pulumi.String("EC2_LINUX"),
+		Tags:         pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := sqs.NewQueue(ctx, fmt.Sprintf("%s-karpenter", clusterName), &sqs.QueueArgs{
+		Name:                    pulumi.String(clusterName),
+		MessageRetentionSeconds: pulumi.Int(300),
+		SqsManagedSseEnabled:    pulumi.Bool(true),
+		Tags:                    pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = sqs.NewQueuePolicy(ctx, fmt.Sprintf("%s-karpenter", clusterName), &sqs.QueuePolicyArgs{
+		QueueUrl: queue.Url,
+		Policy: queue.Arn.ApplyT(func(arn string) (string, error) {
+			return policyDocument([]statement{
+				{
+					Effect: "Allow",
+					Principal: map[string]interface{}{
+						"Service": []string{"events.amazonaws.com", "sqs.amazonaws.com"},
+					},
+					Action:   []string{"sqs:SendMessage"},
+					Resource: []string{arn},
+				},
+			})
+		}).(pulumi.StringOutput),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range _interruptionEvents {
+		name := event.Name
+		eventPattern, err := json.Marshal(map[string][]string{
+			"source":      {event.Source},
+			"detail-type": {event.DetailType},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		rule, err := cloudwatch.NewEventRule(ctx, fmt.Sprintf("%s-karpenter-%s", clusterName, name), &cloudwatch.EventRuleArgs{
+			Name:         pulumi.String(fmt.Sprintf("%s-karpenter-%s", clusterName, name)),
+			EventPattern: pulumi.String(string(eventPattern)),
+			Tags:         pulumi.ToStringMap(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = cloudwatch.NewEventTarget(ctx, fmt.Sprintf("%s-karpenter-%s", clusterName, name), &cloudwatch.EventTargetArgs{
+			Rule: rule.Name,
+			Arn:  queue.Arn,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	controllerPolicy, err := karpenterControllerPolicy(partition.Partition, region.Name, identity.AccountId, clusterName, nodeRoleName)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := CreateServiceAccountRoles(ctx, &ServiceAccountsArgs{
+		Cluster:     args.Cluster,
+		Environment: args.Environment,
+		Tags:        args.Tags,
+		ServiceAccounts: []*ServiceAccount{
+			{
+				Name:           "karpenter",
+				Namespace:      args.Namespace,
+				ServiceAccount: _karpenterServiceAccount,
+				Mode:           ModePodIdentity,
+				Policy:         json.RawMessage(controllerPolicy),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := args.Provider
+	if provider == nil {
+		provider, err = CreateKubernetesProvider(ctx, args.Cluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := helmv3.NewRelease(ctx, fmt.Sprintf("%s-karpenter", clusterName), &helmv3.ReleaseArgs{
+		Name:      pulumi.String("karpenter"),
+		Chart:     pulumi.String(_karpenterChart),
+		Version:   pulumi.String(args.ChartVersion),
+		Namespace: pulumi.String(args.Namespace),
+		Values: pulumi.Map{
+			"settings": pulumi.Map{
+				"clusterName":       args.Cluster.ClusterName,
+				"interruptionQueue": queue.Name,
+			},
+			"serviceAccount": pulumi.Map{
+				"name": pulumi.String(_karpenterServiceAccount),
+			},
+		},
+	}, pulumi.Provider(provider), pulumi.DependsOn(append([]pulumi.Resource{accessEntry}, args.DependsOn...)))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &KarpenterOutput{
+		ControllerRoleArn:   roles.Roles["karpenter"].RoleArn,
+		NodeRoleArn:         nodeRole.Arn,
+		NodeRoleName:        nodeRole.Name,
+		InstanceProfileName: instanceProfile.Name,
+		QueueArn:            queue.Arn,
+		QueueUrl:            queue.Url,
+		Release:             release,
+		Resources:           []pulumi.Resource{release},
+	}
+
+	nodeClassResources := []pulumi.Resource{}
+	for _, nodeClass := range args.NodeClasses {
+		resource, err := apiextensions.NewCustomResource(ctx, fmt.Sprintf("%s-nodeclass-%s", clusterName, nodeClass.Name), &apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("karpenter.k8s.aws/v1"),
+			Kind:       pulumi.String("EC2NodeClass"),
+			Metadata: &metav1.ObjectMetaArgs{
+				Name: pulumi.String(nodeClass.Name),
+			},
+			OtherFields: kubernetes.UntypedArgs{
+				"spec": nodeClassSpec(clusterName, nodeClass, instanceProfile.Name, tags),
+			},
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{release}))
+		if err != nil {
+			return nil, err
+		}
+		nodeClassResources = append(nodeClassResources, resource)
+	}
+
+	for _, nodePool := range args.NodePools {
+		resource, err := apiextensions.NewCustomResource(ctx, fmt.Sprintf("%s-nodepool-%s", clusterName, nodePool.Name), &apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("karpenter.sh/v1"),
+			Kind:       pulumi.String("NodePool"),
+			Metadata: &metav1.ObjectMetaArgs{
+				Name: pulumi.String(nodePool.Name),
+			},
+			OtherFields: kubernetes.UntypedArgs{
+				"spec": nodePoolSpec(nodePool),
+			},
+		}, pulumi.Provider(provider), pulumi.DependsOn(nodeClassResources))
+		if err != nil {
+			return nil, err
+		}
+		output.Resources = append(output.Resources, resource)
+	}
+	output.Resources = append(output.Resources, nodeClassResources...)
+
+	return output, nil
+}
+
+func nodeClassSpec(clusterName string, nodeClass *NodeClass, instanceProfile pulumi.StringOutput, tags map[string]string) pulumi.Map {
+	discovery := pulumi.Array{
+		pulumi.Map{
+			"tags": pulumi.StringMap{
+				vpc.KarpenterDiscoveryTag: pulumi.String(clusterName),
+			},
+		},
+	}
+
+	return pulumi.Map{
+		"instanceProfile": instanceProfile,
+		"amiSelectorTerms": pulumi.Array{
+			pulumi.Map{
+				"alias": pulumi.String(nodeClass.AmiAlias),
+			},
+		},
+		"subnetSelectorTerms":        discovery,
+		"securityGroupSelectorTerms": discovery,
+		"metadataOptions": pulumi.Map{
+			"httpEndpoint":            pulumi.String("enabled"),
+			"httpTokens":              pulumi.String("required"),
+			"httpPutResponseHopLimit": pulumi.Int(2),
+		},
+		"blockDeviceMappings": pulumi.Array{
+			pulumi.Map{
+				"deviceName": pulumi.String("/dev/xvda"),
+				"ebs": pulumi.Map{
+					"volumeSize":          pulumi.String(fmt.Sprintf("%dGi", nodeClass.VolumeSize)),
+					"volumeType":          pulumi.String("gp3"),
+					"encrypted":           pulumi.Bool(true),
+					"deleteOnTermination": pulumi.Bool(true),
+				},
+			},
+		},
+		"tags": pulumi.ToStringMap(merge(tags, nodeClass.Tags)),
+	}
+}
+
+func nodePoolSpec(nodePool *NodePool) pulumi.Map {
+	requirement := func(key string, values []string) pulumi.Map {
+		return pulumi.Map{
+			"key":      pulumi.String(key),
+			"operator": pulumi.String("In"),
+			"values":   pulumi.ToStringArray(values),
+		}
+	}
+
+	taints := pulumi.Array{}
+	for _, taint := range nodePool.Taints {
+		taintMap := pulumi.Map{
+			"key":    pulumi.String(taint.Key),
+			"effect": pulumi.String(taint.Effect),
+		}
+		if taint.Value != "" {
+			taintMap["value"] = pulumi.String(taint.Value)
+		}
+		taints = append(taints, taintMap)
+	}
+
+	spec := pulumi.Map{
+		"template": pulumi.Map{
+			"metadata": pulumi.Map{
+				"labels": pulumi.ToStringMap(nodePool.Labels),
+			},
+			"spec": pulumi.Map{
+				"nodeClassRef": pulumi.Map{
+					"group": pulumi.String("karpenter.k8s.aws"),
+					"kind":  pulumi.String("EC2NodeClass"),
+					"name":  pulumi.String(nodePool.NodeClass),
+				},
+				"requirements": pulumi.Array{
+					requirement("karpenter.k8s.aws/instance-category", nodePool.InstanceCategories),
+					requirement("karpenter.sh/capacity-type", nodePool.CapacityTypes),
+					requirement("kubernetes.io/arch", nodePool.Architectures),
+				},
+				"taints":      taints,
+				"expireAfter": pulumi.String(nodePool.ExpireAfter),
+			},
+		},
+		"disruption": pulumi.Map{
+			"consolidationPolicy": pulumi.String(nodePool.ConsolidationPolicy),
+			"consolidateAfter":    pulumi.String(nodePool.ConsolidateAfter),
+		},
+	}
+	if nodePool.CpuLimit > 0 {
+		spec["limits"] = pulumi.Map{
+			"cpu": pulumi.String(fmt.Sprintf("%d", nodePool.CpuLimit)),
+		}
+	}
+
+	return spec
+}
+
+// karpenterControllerPolicy lets the controller launch and terminate the
+// nodes it owns, pass the node role and read the interruption queue. Like the
+// upstream policy, everything it creates must carry the cluster's ownership
+// tag, and it may only tag, terminate or delete resources that already do.
+func karpenterControllerPolicy(partition, region, accountId, clusterName, nodeRoleName string) (string, error) {
+	ec2Arn := func(resourceType string) string {
+		return fmt.Sprintf("arn:%s:ec2:%s:*:%s/*", partition, region, resourceType)
+	}
+	ownedResources := []string{
+		ec2Arn("fleet"),
+		ec2Arn("instance"),
+		ec2Arn("volume"),
+		ec2Arn("network-interface"),
+		ec2Arn("launch-template"),
+		ec2Arn("spot-instances-request"),
+	}
+	clusterTag := fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)
+
+	return policyDocument([]statement{
+		{
+			Effect: "Allow",
+			Action: []string{
+				"ec2:DescribeAvailabilityZones",
+				"ec2:DescribeImages",
+				"ec2:DescribeInstances",
+				"ec2:DescribeInstanceTypeOfferings",
+				"ec2:DescribeInstanceTypes",
+				"ec2:DescribeLaunchTemplates",
+				"ec2:DescribeSecurityGroups",
+				"ec2:DescribeSpotPriceHistory",
+				"ec2:DescribeSubnets",
+				"pricing:GetProducts",
+			},
+			Resource: []string{"*"},
+		},
+		{
+			// the existing resources a launch references
+			Effect: "Allow",
+			Action: []string{"ec2:CreateFleet", "ec2:RunInstances"},
+			Resource: []string{
+				fmt.Sprintf("arn:%s:ec2:%s::image/*", partition, region),
+				fmt.Sprintf("arn:%s:ec2:%s::snapshot/*", partition, region),
+				ec2Arn("security-group"),
+				ec2Arn("subnet"),
+				ec2Arn("capacity-reservation"),
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"ec2:CreateFleet", "ec2:RunInstances"},
+			Resource: []string{ec2Arn("launch-template")},
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					fmt.Sprintf("aws:ResourceTag/%s", clusterTag): "owned",
+				},
+				"StringLike": {
+					fmt.Sprintf("aws:ResourceTag/%s", _karpenterNodePoolTag): "*",
+				},
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"ec2:CreateFleet", "ec2:CreateLaunchTemplate", "ec2:RunInstances"},
+			Resource: ownedResources,
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					fmt.Sprintf("aws:RequestTag/%s", clusterTag): "owned",
+				},
+				"StringLike": {
+					fmt.Sprintf("aws:RequestTag/%s", _karpenterNodePoolTag): "*",
+				},
+			},
+		},
+		{
+			// tags set while creating the resources above
+			Effect:   "Allow",
+			Action:   []string{"ec2:CreateTags"},
+			Resource: ownedResources,
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					fmt.Sprintf("aws:RequestTag/%s", clusterTag): "owned",
+					"ec2:CreateAction":                           []string{"CreateFleet", "CreateLaunchTemplate", "RunInstances"},
+				},
+				"StringLike": {
+					fmt.Sprintf("aws:RequestTag/%s", _karpenterNodePoolTag): "*",
+				},
+			},
+		},
+		{
+			// tags added later, e.g. the node claim, on instances it owns
+			Effect:   "Allow",
+			Action:   []string{"ec2:CreateTags"},
+			Resource: []string{ec2Arn("instance")},
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					fmt.Sprintf("aws:ResourceTag/%s", clusterTag): "owned",
+				},
+				"StringLike": {
+					fmt.Sprintf("aws:ResourceTag/%s", _karpenterNodePoolTag): "*",
+				},
+				"ForAllValues:StringEquals": {
+					"aws:TagKeys": []string{"eks:eks-cluster-name", "karpenter.sh/nodeclaim", "Name"},
+				},
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"ec2:DeleteLaunchTemplate", "ec2:TerminateInstances"},
+			Resource: []string{ec2Arn("instance"), ec2Arn("launch-template")},
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					fmt.Sprintf("aws:ResourceTag/%s", clusterTag): "owned",
+				},
+				"StringLike": {
+					fmt.Sprintf("aws:ResourceTag/%s", _karpenterNodePoolTag): "*",
+				},
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"iam:PassRole"},
+			Resource: []string{fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, accountId, nodeRoleName)},
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {
+					"iam:PassedToService": "ec2.amazonaws.com",
+				},
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"iam:GetInstanceProfile"},
+			Resource: []string{"*"},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"eks:DescribeCluster"},
+			Resource: []string{fmt.Sprintf("arn:%s:eks:%s:%s:cluster/%s", partition, region, accountId, clusterName)},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"sqs:DeleteMessage", "sqs:GetQueueUrl", "sqs:ReceiveMessage"},
+			Resource: []string{fmt.Sprintf("arn:%s:sqs:%s:%s:%s", partition, region, accountId, clusterName)},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"ssm:GetParameter"},
+			Resource: []string{fmt.Sprintf("arn:%s:ssm:%s::parameter/aws/service/*", partition, region)},
+		},
+	})
+}
+
+func setNodeClassDefaults(nodeClass *NodeClass) {
+	if nodeClass.AmiAlias == "" {
+		nodeClass.AmiAlias = "al2023@latest"
+	}
+	if nodeClass.VolumeSize == 0 {
+		nodeClass.VolumeSize = 20
+	}
+}
+
+func setNodePoolDefaults(nodePool *NodePool) {
+	if nodePool.NodeClass == "" {
+		nodePool.NodeClass = "default"
+	}
+	if len(nodePool.InstanceCategories) == 0 {
+		nodePool.InstanceCategories = []string{"c", "m", "r"}
+	}
+	if len(nodePool.CapacityTypes) == 0 {
+		nodePool.CapacityTypes = []string{"on-demand"}
+	}
+	if len(nodePool.Architectures) == 0 {
+		nodePool.Architectures = []string{"amd64"}
+	}
+	if nodePool.ConsolidationPolicy == "" {
+		nodePool.ConsolidationPolicy = "WhenEmptyOrUnderutilized"
+	}
+	if nodePool.ConsolidateAfter == "" {
+		nodePool.ConsolidateAfter = "1m"
+	}
+	if nodePool.ExpireAfter == "" {
+		nodePool.ExpireAfter = "720h"
+	}
+}
+
+func validateNodePool(nodePool *NodePool, nodeClasses map[string]bool) error {
+	if nodePool.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if !nodeClasses[nodePool.NodeClass] {
+		return fmt.Errorf("unknown node class %q", nodePool.NodeClass)
+	}
+	for _, capacityType := range nodePool.CapacityTypes {
+		if capacityType != "on-demand" && capacityType != "spot" {
+			return fmt.Errorf("unsupported capacity type %q", capacityType)
+		}
+	}
+	if !contains(_consolidationPolicies, nodePool.ConsolidationPolicy) {
+		return fmt.Errorf("unsupported consolidation policy %q", nodePool.ConsolidationPolicy)
+	}
+	if nodePool.CpuLimit < 0 {
+		return fmt.Errorf("cpu limit cannot be negative")
+	}
+	for _, taint := range nodePool.Taints {
+		if taint.Key == "" {
+			return fmt.Errorf("taint key cannot be empty")
+		}
+		if !contains(_kubernetesTaintEffects, taint.Effect) {
+			return fmt.Errorf("unsupported taint effect %q", taint.Effect)
+		}
+	}
+
+	return nil
+}
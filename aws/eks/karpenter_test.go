@@ -0,0 +1,45 @@
+package eks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestKarpenterControllerPolicyScopesMutations(t *testing.T) {
+	document, err := karpenterControllerPolicy("aws", "eu-west-1", "123456789012", "main", "main-karpenter-node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var policy struct {
+		Statement []statement
+	}
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// actions that must never be granted on every resource without a tag
+	// condition
+	scoped := map[string]bool{
+		"ec2:CreateTags":           true,
+		"ec2:CreateLaunchTemplate": true,
+		"ec2:TerminateInstances":   true,
+		"ec2:DeleteLaunchTemplate": true,
+		"iam:PassRole":             true,
+	}
+	for _, s := range policy.Statement {
+		for _, action := range s.Action {
+			if !scoped[action] {
+				continue
+			}
+			if len(s.Condition) == 0 {
+				t.Errorf("%s is granted without a condition", action)
+			}
+			for _, resource := range s.Resource {
+				if resource == "*" {
+					t.Errorf("%s is granted on every resource", action)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package eks
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// CreateKubernetesProvider returns a provider that talks to the cluster with
+// its generated kubeconfig, for Helm releases and manifests.
+func CreateKubernetesProvider(ctx *pulumi.Context, cluster *ClusterOutput) (*kubernetes.Provider, error) {
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	return kubernetes.NewProvider(ctx, fmt.Sprintf("%s-k8s", cluster.Name), &kubernetes.ProviderArgs{
+		Kubeconfig: cluster.Kubeconfig,
+	})
+}
@@ -0,0 +1,235 @@
+package eks
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	helmv3 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	targetgroup "github.com/tungnt76/pulumi-in-go/aws/elb/target-group"
+)
+
+const (
+	_loadBalancerControllerChart          = "aws-load-balancer-controller"
+	_loadBalancerControllerChartVersion   = "1.9.2"
+	_loadBalancerControllerServiceAccount = "aws-load-balancer-controller"
+	_eksChartsRepository                  = "https://aws.github.io/eks-charts"
+)
+
+// _loadBalancerControllerPolicy is the upstream IAM policy of the controller.
+//
+//go:embed policies/load-balancer-controller.json
+var _loadBalancerControllerPolicy []byte
+
+var _bindingTargetTypes = []string{"ip", "instance"}
+
+type LoadBalancerControllerArgs struct {
+	Cluster     *ClusterOutput
+	Environment string
+	Tags        map[string]string
+	// Namespace defaults to kube-system.
+	Namespace    string
+	ChartVersion string
+	// Provider is created from the cluster kubeconfig when nil.
+	Provider *kubernetes.Provider
+	// DependsOn are resources the controller needs first, e.g. node groups
+	// and the pod identity agent add-on.
+	DependsOn []pulumi.Resource
+	// TargetGroupBindings register Kubernetes services into target groups
+	// that are managed in Go, next to the ALB, listener rules and
+	// certificates.
+	TargetGroupBindings []*TargetGroupBinding
+}
+
+type TargetGroupBinding struct {
+	Name        string
+	Namespace   string
+	ServiceName string
+	ServicePort int
+	// TargetGroup is the output of targetgroup.CreateTargetGroup.
+	TargetGroup *targetgroup.TargetGroupOutput
+	// TargetType is ip or instance and must match the target group.
+	TargetType string
+	// SecurityGroupId, usually the ALB's, is allowed to reach the targets.
+	SecurityGroupId string
+}
+
+type LoadBalancerControllerOutput struct {
+	RoleArn   pulumi.StringOutput
+	Release   *helmv3.Release
+	Bindings  map[string]*apiextensions.CustomResource
+	Resources []pulumi.Resource
+}
+
+func CreateLoadBalancerController(ctx *pulumi.Context, args *LoadBalancerControllerArgs) (*LoadBalancerControllerOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	if args.Namespace == "" {
+		args.Namespace = "kube-system"
+	}
+	if args.ChartVersion == "" {
+		args.ChartVersion = _loadBalancerControllerChartVersion
+	}
+
+	names := map[string]bool{}
+	for _, binding := range args.TargetGroupBindings {
+		if binding.TargetType == "" {
+			binding.TargetType = "ip"
+		}
+		if err := validateTargetGroupBinding(binding); err != nil {
+			return nil, fmt.Errorf("target group binding %s: %w", binding.Name, err)
+		}
+		key := fmt.Sprintf("%s/%s", binding.Namespace, binding.Name)
+		if names[key] {
+			return nil, fmt.Errorf("target group binding %s is defined more than once", key)
+		}
+		names[key] = true
+	}
+
+	region, err := aws.GetRegion(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := CreateServiceAccountRoles(ctx, &ServiceAccountsArgs{
+		Cluster:     args.Cluster,
+		Environment: args.Environment,
+		Tags:        args.Tags,
+		ServiceAccounts: []*ServiceAccount{
+			{
+				Name:           _loadBalancerControllerChart,
+				Namespace:      args.Namespace,
+				ServiceAccount: _loadBalancerControllerServiceAccount,
+				Mode:           ModePodIdentity,
+				Policy:         json.RawMessage(_loadBalancerControllerPolicy),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	role := roles.Roles[_loadBalancerControllerChart]
+
+	provider := args.Provider
+	if provider == nil {
+		provider, err = CreateKubernetesProvider(ctx, args.Cluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := helmv3.NewRelease(ctx, fmt.Sprintf("%s-%s", args.Cluster.Name, _loadBalancerControllerChart), &helmv3.ReleaseArgs{
+		Name:      pulumi.String(_loadBalancerControllerChart),
+		Chart:     pulumi.String(_loadBalancerControllerChart),
+		Version:   pulumi.String(args.ChartVersion),
+		Namespace: pulumi.String(args.Namespace),
+		RepositoryOpts: &helmv3.RepositoryOptsArgs{
+			Repo: pulumi.String(_eksChartsRepository),
+		},
+		Values: pulumi.Map{
+			"clusterName": args.Cluster.ClusterName,
+			"region":      pulumi.String(region.Name),
+			"vpcId":       args.Cluster.Vpc.VpcId,
+			"serviceAccount": pulumi.Map{
+				"create": pulumi.Bool(true),
+				"name":   pulumi.String(_loadBalancerControllerServiceAccount),
+			},
+		},
+	}, pulumi.Provider(provider), pulumi.DependsOn(args.DependsOn))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &LoadBalancerControllerOutput{
+		RoleArn:   role.RoleArn,
+		Release:   release,
+		Bindings:  map[string]*apiextensions.CustomResource{},
+		Resources: []pulumi.Resource{release},
+	}
+
+	for _, binding := range args.TargetGroupBindings {
+		spec := pulumi.Map{
+			"serviceRef": pulumi.Map{
+				"name": pulumi.String(binding.ServiceName),
+				"port": pulumi.Int(binding.ServicePort),
+			},
+			"targetGroupARN": binding.TargetGroup.TargetGroupArn,
+			"targetType":     pulumi.String(binding.TargetType),
+		}
+		if binding.SecurityGroupId != "" {
+			spec["networking"] = pulumi.Map{
+				"ingress": pulumi.Array{
+					pulumi.Map{
+						"from": pulumi.Array{
+							pulumi.Map{
+								"securityGroup": pulumi.Map{
+									"groupID": pulumi.String(binding.SecurityGroupId),
+								},
+							},
+						},
+						"ports": pulumi.Array{
+							pulumi.Map{
+								"protocol": pulumi.String("TCP"),
+							},
+						},
+					},
+				},
+			}
+		}
+
+		// the CRD and its webhook come with the chart
+		resource, err := apiextensions.NewCustomResource(ctx, fmt.Sprintf("%s-tgb-%s-%s", args.Cluster.Name, binding.Namespace, binding.Name), &apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("elbv2.k8s.aws/v1beta1"),
+			Kind:       pulumi.String("TargetGroupBinding"),
+			Metadata: &metav1.ObjectMetaArgs{
+				Name:      pulumi.String(binding.Name),
+				Namespace: pulumi.String(binding.Namespace),
+			},
+			OtherFields: kubernetes.UntypedArgs{
+				"spec": spec,
+			},
+		}, pulumi.Provider(provider), pulumi.DependsOn([]pulumi.Resource{release}))
+		if err != nil {
+			return nil, err
+		}
+
+		output.Bindings[fmt.Sprintf("%s/%s", binding.Namespace, binding.Name)] = resource
+		output.Resources = append(output.Resources, resource)
+	}
+
+	return output, nil
+}
+
+func validateTargetGroupBinding(binding *TargetGroupBinding) error {
+	if binding.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if binding.Namespace == "" {
+		return fmt.Errorf("namespace cannot be empty")
+	}
+	if binding.ServiceName == "" {
+		return fmt.Errorf("service name cannot be empty")
+	}
+	if binding.ServicePort < 1 || binding.ServicePort > 65535 {
+		return fmt.Errorf("service port must be between 1 and 65535, got %d", binding.ServicePort)
+	}
+	if binding.TargetGroup == nil {
+		return fmt.Errorf("target group cannot be nil")
+	}
+	if !contains(_bindingTargetTypes, binding.TargetType) {
+		return fmt.Errorf("unsupported target type %q", binding.TargetType)
+	}
+
+	return nil
+}
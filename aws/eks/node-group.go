@@ -0,0 +1,354 @@
+package eks
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+const (
+	CapacityOnDemand = "ON_DEMAND"
+	CapacitySpot     = "SPOT"
+
+	// managed node groups merge custom user data into their own, which only
+	// works for MIME multi-part documents
+	_userDataBoundary = "==BOUNDARY=="
+)
+
+var _capacityTypes = []string{CapacityOnDemand, CapacitySpot}
+
+var _taintEffects = []string{"NO_SCHEDULE", "NO_EXECUTE", "PREFER_NO_SCHEDULE"}
+
+var _volumeTypes = []string{"gp2", "gp3", "io1", "io2"}
+
+var _nodePolicies = []string{
+	"AmazonEKSWorkerNodePolicy",
+	"AmazonEKS_CNI_Policy",
+	"AmazonEC2ContainerRegistryReadOnly",
+	"AmazonSSMManagedInstanceCore",
+}
+
+type NodeGroup struct {
+	Name          string   `json:"name"`
+	InstanceTypes []string `json:"instance_types"`
+	// CapacityType is ON_DEMAND or SPOT, defaulting to ON_DEMAND.
+	CapacityType string `json:"capacity_type"`
+	AmiType      string `json:"ami_type"`
+
+	MinSize     int `json:"min_size"`
+	MaxSize     int `json:"max_size"`
+	DesiredSize int `json:"desired_size"`
+
+	Labels map[string]string `json:"labels"`
+	Taints []*Taint          `json:"taints"`
+
+	// DiskSize is the root volume size in GiB, defaulting to 20.
+	DiskSize int    `json:"disk_size"`
+	DiskType string `json:"disk_type"`
+	// DiskEncrypted defaults to true; DiskKmsKeyArn defaults to the EBS
+	// managed key.
+	DiskEncrypted *bool  `json:"disk_encrypted"`
+	DiskKmsKeyArn string `json:"disk_kms_key_arn"`
+	// UserData runs before the node joins the cluster. Plain scripts are
+	// wrapped in a MIME multi-part document.
+	UserData string `json:"user_data"`
+}
+
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+type NodeGroupsArgs struct {
+	Cluster     *ClusterOutput
+	Environment string
+	Tags        map[string]string
+	Groups      []*NodeGroup
+}
+
+type NodeGroupOutput struct {
+	NodeGroupArn     pulumi.StringOutput
+	LaunchTemplateId pulumi.IDOutput
+}
+
+type NodeGroupsOutput struct {
+	// NodeRoleArn is shared by every group.
+	NodeRoleArn  pulumi.StringOutput
+	NodeRoleName pulumi.StringOutput
+	NodeGroups   map[string]*NodeGroupOutput
+	// Resources are the node groups, for add-ons and workloads that need
+	// nodes to schedule on.
+	Resources []pulumi.Resource
+}
+
+// NodeGroupsFromConfig reads the node groups under `<namespace>:node_groups`.
+func NodeGroupsFromConfig(ctx *pulumi.Context, namespace string) ([]*NodeGroup, error) {
+	groups := []*NodeGroup{}
+	if err := config.New(ctx, namespace).GetObject("node_groups", &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func CreateNodeGroups(ctx *pulumi.Context, args *NodeGroupsArgs) (*NodeGroupsOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	// defaults are set on copies so the caller's groups are left untouched
+	groups := []*NodeGroup{}
+	names := map[string]bool{}
+	for _, group := range args.Groups {
+		group := *group
+		setNodeGroupDefaults(&group)
+		if err := validateNodeGroup(&group); err != nil {
+			return nil, fmt.Errorf("node group %s: %w", group.Name, err)
+		}
+		if names[group.Name] {
+			return nil, fmt.Errorf("node group %s is defined more than once", group.Name)
+		}
+		names[group.Name] = true
+		groups = append(groups, &group)
+	}
+
+	tags := merge(map[string]string{
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	partition, err := aws.GetPartition(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterName := args.Cluster.Name
+
+	role, err := iam.NewRole(ctx, fmt.Sprintf("%s-node-role", clusterName), &iam.RoleArgs{
+		Name:             pulumi.String(fmt.Sprintf("%s-node-role", clusterName)),
+		AssumeRolePolicy: pulumi.String(assumeRolePolicy("ec2.amazonaws.com")),
+		Tags:             pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := []pulumi.Resource{}
+	for _, policy := range _nodePolicies {
+		attachment, err := iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-node-role-%s", clusterName, policy), &iam.RolePolicyAttachmentArgs{
+			Role:      role.Name,
+			PolicyArn: pulumi.String(fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition.Partition, policy)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	output := &NodeGroupsOutput{
+		NodeRoleArn:  role.Arn,
+		NodeRoleName: role.Name,
+		NodeGroups:   map[string]*NodeGroupOutput{},
+	}
+
+	for _, group := range groups {
+		groupTags := merge(tags, map[string]string{
+			"Name": group.Name,
+		})
+
+		ebs := &ec2.LaunchTemplateBlockDeviceMappingEbsArgs{
+			VolumeSize:          pulumi.Int(group.DiskSize),
+			VolumeType:          pulumi.String(group.DiskType),
+			Encrypted:           pulumi.String(strconv.FormatBool(*group.DiskEncrypted)),
+			DeleteOnTermination: pulumi.String("true"),
+		}
+		if group.DiskKmsKeyArn != "" {
+			ebs.KmsKeyId = pulumi.String(group.DiskKmsKeyArn)
+		}
+
+		launchTemplateArgs := &ec2.LaunchTemplateArgs{
+			NamePrefix:           pulumi.String(fmt.Sprintf("%s-%s-", clusterName, group.Name)),
+			UpdateDefaultVersion: pulumi.Bool(true),
+			MetadataOptions: &ec2.LaunchTemplateMetadataOptionsArgs{
+				HttpEndpoint: pulumi.String("enabled"),
+				HttpTokens:   pulumi.String("required"),
+				// pods reach the metadata service through one extra hop
+				HttpPutResponseHopLimit: pulumi.Int(2),
+			},
+			BlockDeviceMappings: ec2.LaunchTemplateBlockDeviceMappingArray{
+				&ec2.LaunchTemplateBlockDeviceMappingArgs{
+					DeviceName: pulumi.String("/dev/xvda"),
+					Ebs:        ebs,
+				},
+			},
+			TagSpecifications: ec2.LaunchTemplateTagSpecificationArray{
+				&ec2.LaunchTemplateTagSpecificationArgs{
+					ResourceType: pulumi.String("instance"),
+					Tags:         pulumi.ToStringMap(groupTags),
+				},
+				&ec2.LaunchTemplateTagSpecificationArgs{
+					ResourceType: pulumi.String("volume"),
+					Tags:         pulumi.ToStringMap(groupTags),
+				},
+			},
+			Tags: pulumi.ToStringMap(groupTags),
+		}
+		if group.UserData != "" {
+			launchTemplateArgs.UserData = pulumi.String(base64.StdEncoding.EncodeToString([]byte(multipartUserData(group.UserData))))
+		}
+
+		launchTemplate, err := ec2.NewLaunchTemplate(ctx, fmt.Sprintf("%s-%s-lt", clusterName, group.Name), launchTemplateArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		taints := eks.NodeGroupTaintArray{}
+		for _, taint := range group.Taints {
+			taintArgs := &eks.NodeGroupTaintArgs{
+				Key:    pulumi.String(taint.Key),
+				Effect: pulumi.String(taint.Effect),
+			}
+			if taint.Value != "" {
+				taintArgs.Value = pulumi.String(taint.Value)
+			}
+			taints = append(taints, taintArgs)
+		}
+
+		nodeGroupArgs := &eks.NodeGroupArgs{
+			ClusterName:   args.Cluster.ClusterName,
+			NodeGroupName: pulumi.String(group.Name),
+			NodeRoleArn:   role.Arn,
+			SubnetIds:     args.Cluster.Vpc.PrivateSubnetIds,
+			InstanceTypes: pulumi.ToStringArray(group.InstanceTypes),
+			CapacityType:  pulumi.String(group.CapacityType),
+			ScalingConfig: &eks.NodeGroupScalingConfigArgs{
+				MinSize:     pulumi.Int(group.MinSize),
+				MaxSize:     pulumi.Int(group.MaxSize),
+				DesiredSize: pulumi.Int(group.DesiredSize),
+			},
+			UpdateConfig: &eks.NodeGroupUpdateConfigArgs{
+				MaxUnavailable: pulumi.Int(1),
+			},
+			LaunchTemplate: &eks.NodeGroupLaunchTemplateArgs{
+				Id: launchTemplate.ID(),
+				Version: launchTemplate.LatestVersion.ApplyT(func(version int) string {
+					return strconv.Itoa(version)
+				}).(pulumi.StringOutput),
+			},
+			Labels: pulumi.ToStringMap(group.Labels),
+			Taints: taints,
+			Tags:   pulumi.ToStringMap(groupTags),
+		}
+		if group.AmiType != "" {
+			nodeGroupArgs.AmiType = pulumi.String(group.AmiType)
+		}
+
+		// the desired size is left to autoscalers once the group exists
+		nodeGroup, err := eks.NewNodeGroup(ctx, fmt.Sprintf("%s-%s", clusterName, group.Name), nodeGroupArgs,
+			pulumi.DependsOn(attachments),
+			pulumi.IgnoreChanges([]string{"scalingConfig.desiredSize"}),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		output.NodeGroups[group.Name] = &NodeGroupOutput{
+			NodeGroupArn:     nodeGroup.Arn,
+			LaunchTemplateId: launchTemplate.ID(),
+		}
+		output.Resources = append(output.Resources, nodeGroup)
+	}
+
+	return output, nil
+}
+
+func setNodeGroupDefaults(group *NodeGroup) {
+	if len(group.InstanceTypes) == 0 {
+		group.InstanceTypes = []string{"t3.medium"}
+	}
+	if group.CapacityType == "" {
+		group.CapacityType = CapacityOnDemand
+	}
+	if group.MinSize == 0 && group.MaxSize == 0 {
+		group.MinSize = 1
+		group.MaxSize = 3
+	}
+	if group.DesiredSize == 0 {
+		group.DesiredSize = group.MinSize
+	}
+	if group.DiskSize == 0 {
+		group.DiskSize = 20
+	}
+	if group.DiskType == "" {
+		group.DiskType = "gp3"
+	}
+	if group.DiskEncrypted == nil {
+		encrypted := true
+		group.DiskEncrypted = &encrypted
+	}
+}
+
+func validateNodeGroup(group *NodeGroup) error {
+	if group.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if !contains(_capacityTypes, group.CapacityType) {
+		return fmt.Errorf("unsupported capacity type %q", group.CapacityType)
+	}
+	if group.MaxSize < 1 {
+		return fmt.Errorf("max size must be at least 1, got %d", group.MaxSize)
+	}
+	if group.MinSize < 0 || group.MinSize > group.MaxSize {
+		return fmt.Errorf("min size must be between 0 and max size %d, got %d", group.MaxSize, group.MinSize)
+	}
+	if group.DesiredSize < group.MinSize || group.DesiredSize > group.MaxSize {
+		return fmt.Errorf("desired size must be between %d and %d, got %d", group.MinSize, group.MaxSize, group.DesiredSize)
+	}
+	if !contains(_volumeTypes, group.DiskType) {
+		return fmt.Errorf("unsupported disk type %q", group.DiskType)
+	}
+	if group.DiskKmsKeyArn != "" && !*group.DiskEncrypted {
+		return fmt.Errorf("disk kms key needs an encrypted disk")
+	}
+	for _, taint := range group.Taints {
+		if taint.Key == "" {
+			return fmt.Errorf("taint key cannot be empty")
+		}
+		if !contains(_taintEffects, taint.Effect) {
+			return fmt.Errorf("unsupported taint effect %q", taint.Effect)
+		}
+	}
+
+	return nil
+}
+
+// multipartUserData wraps a shell script in the MIME multi-part document
+// managed node groups expect, and leaves documents that already are one
+// untouched.
+func multipartUserData(userData string) string {
+	if strings.HasPrefix(userData, "MIME-Version:") {
+		return userData
+	}
+
+	return fmt.Sprintf(`MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="%[1]s"
+
+--%[1]s
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+%[2]s
+
+--%[1]s--
+`, _userDataBoundary, userData)
+}
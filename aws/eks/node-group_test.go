@@ -0,0 +1,165 @@
+package eks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+// mocks echoes the inputs of every resource and invoke.
+type mocks struct{}
+
+func (mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name + "-id", args.Inputs.Copy(), nil
+}
+
+func (mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestSetNodeGroupDefaults(t *testing.T) {
+	encrypted := false
+
+	tests := []struct {
+		name  string
+		group NodeGroup
+		want  NodeGroup
+	}{
+		{
+			name:  "empty",
+			group: NodeGroup{Name: "default"},
+			want: NodeGroup{
+				Name:          "default",
+				InstanceTypes: []string{"t3.medium"},
+				CapacityType:  CapacityOnDemand,
+				MinSize:       1,
+				MaxSize:       3,
+				DesiredSize:   1,
+				DiskSize:      20,
+				DiskType:      "gp3",
+			},
+		},
+		{
+			name: "explicit values are kept",
+			group: NodeGroup{
+				Name:          "spot",
+				InstanceTypes: []string{"m6i.large"},
+				CapacityType:  CapacitySpot,
+				MinSize:       2,
+				MaxSize:       10,
+				DesiredSize:   4,
+				DiskSize:      100,
+				DiskType:      "io2",
+				DiskEncrypted: &encrypted,
+			},
+			want: NodeGroup{
+				Name:          "spot",
+				InstanceTypes: []string{"m6i.large"},
+				CapacityType:  CapacitySpot,
+				MinSize:       2,
+				MaxSize:       10,
+				DesiredSize:   4,
+				DiskSize:      100,
+				DiskType:      "io2",
+			},
+		},
+		{
+			name:  "desired size follows min size",
+			group: NodeGroup{Name: "batch", MinSize: 0, MaxSize: 5},
+			want: NodeGroup{
+				Name:          "batch",
+				InstanceTypes: []string{"t3.medium"},
+				CapacityType:  CapacityOnDemand,
+				MaxSize:       5,
+				DiskSize:      20,
+				DiskType:      "gp3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := tt.group
+			setNodeGroupDefaults(&group)
+			if group.DiskEncrypted == nil {
+				t.Fatal("disk encrypted is not set")
+			}
+			wantEncrypted := tt.group.DiskEncrypted == nil || *tt.group.DiskEncrypted
+			if *group.DiskEncrypted != wantEncrypted {
+				t.Errorf("got disk encrypted %v, want %v", *group.DiskEncrypted, wantEncrypted)
+			}
+			group.DiskEncrypted = nil
+			if !reflect.DeepEqual(group, tt.want) {
+				t.Errorf("got %+v, want %+v", group, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateNodeGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   NodeGroup
+		wantErr string
+	}{
+		{name: "defaults", group: NodeGroup{Name: "default"}},
+		{name: "no name", group: NodeGroup{}, wantErr: "name cannot be empty"},
+		{name: "unknown capacity type", group: NodeGroup{Name: "a", CapacityType: "RESERVED"}, wantErr: "unsupported capacity type"},
+		{name: "min above max", group: NodeGroup{Name: "a", MinSize: 4, MaxSize: 2}, wantErr: "min size must be between"},
+		{name: "desired above max", group: NodeGroup{Name: "a", MinSize: 1, MaxSize: 2, DesiredSize: 3}, wantErr: "desired size must be between"},
+		{name: "unknown disk type", group: NodeGroup{Name: "a", DiskType: "st1"}, wantErr: "unsupported disk type"},
+		{name: "taint without key", group: NodeGroup{Name: "a", Taints: []*Taint{{Effect: "NO_SCHEDULE"}}}, wantErr: "taint key cannot be empty"},
+		{name: "unknown taint effect", group: NodeGroup{Name: "a", Taints: []*Taint{{Key: "gpu", Effect: "NoSchedule"}}}, wantErr: "unsupported taint effect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := tt.group
+			setNodeGroupDefaults(&group)
+			err := validateNodeGroup(&group)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateNodeGroupsLeavesGroupsUntouched(t *testing.T) {
+	groups := []*NodeGroup{{Name: "default"}, {Name: "spot", CapacityType: CapacitySpot}}
+	want := []NodeGroup{*groups[0], *groups[1]}
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := CreateNodeGroups(ctx, &NodeGroupsArgs{
+			Cluster: &ClusterOutput{
+				Name:        "main",
+				ClusterName: pulumi.String("main").ToStringOutput(),
+				Vpc: &vpc.VpcOutput{
+					PrivateSubnetIds: pulumi.StringArray{pulumi.String("subnet-a"), pulumi.String("subnet-b")},
+				},
+			},
+			Environment: "dev",
+			Groups:      groups,
+		})
+		return err
+	}, pulumi.WithMocks("project", "stack", mocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, group := range groups {
+		if !reflect.DeepEqual(*group, want[i]) {
+			t.Errorf("group %s was changed to %+v", group.Name, *group)
+		}
+	}
+}
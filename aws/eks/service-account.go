@@ -0,0 +1,290 @@
+package eks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/eks"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/iam"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+const (
+	// ModeIrsa binds the role through the cluster OIDC provider, ModePodIdentity
+	// through the EKS Pod Identity agent.
+	ModeIrsa        = "irsa"
+	ModePodIdentity = "pod_identity"
+
+	// IAM trusts the EKS OIDC issuers through its own CA library, but still
+	// requires a thumbprint, so the issuers' root CA is used.
+	_oidcThumbprint = "9e99a48a9960b14926bb7f3b02e22da2b0ab7280"
+
+	_maxRoleNameLength = 64
+)
+
+var _serviceAccountModes = []string{ModeIrsa, ModePodIdentity}
+
+type ServiceAccount struct {
+	// Name is used in the role name, defaulting to
+	// <namespace>-<service account>.
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"service_account"`
+	// Mode is irsa or pod_identity, defaulting to pod_identity.
+	Mode       string   `json:"mode"`
+	PolicyArns []string `json:"policy_arns"`
+	// Policy is an inline IAM policy document.
+	Policy json.RawMessage `json:"policy"`
+}
+
+type ServiceAccountsArgs struct {
+	Cluster     *ClusterOutput
+	Environment string
+	Tags        map[string]string
+	// OidcProvider is created when nil and any service account uses IRSA.
+	OidcProvider    *OidcProviderOutput
+	ServiceAccounts []*ServiceAccount
+}
+
+type OidcProviderOutput struct {
+	Arn pulumi.StringOutput
+	// Issuer is the issuer URL without its scheme, as used in trust policy
+	// conditions.
+	Issuer pulumi.StringOutput
+}
+
+type ServiceAccountRole struct {
+	RoleArn  pulumi.StringOutput
+	RoleName pulumi.StringOutput
+}
+
+type ServiceAccountsOutput struct {
+	OidcProvider *OidcProviderOutput
+	Roles        map[string]*ServiceAccountRole
+}
+
+// ServiceAccountsFromConfig reads the service accounts under
+// `<namespace>:service_accounts`.
+func ServiceAccountsFromConfig(ctx *pulumi.Context, namespace string) ([]*ServiceAccount, error) {
+	serviceAccounts := []*ServiceAccount{}
+	if err := config.New(ctx, namespace).GetObject("service_accounts", &serviceAccounts); err != nil {
+		return nil, err
+	}
+
+	return serviceAccounts, nil
+}
+
+// CreateOidcProvider registers the cluster's OIDC issuer with IAM. It can only
+// exist once per cluster.
+func CreateOidcProvider(ctx *pulumi.Context, cluster *ClusterOutput, tags map[string]string) (*OidcProviderOutput, error) {
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	provider, err := iam.NewOpenIdConnectProvider(ctx, fmt.Sprintf("%s-oidc", cluster.Name), &iam.OpenIdConnectProviderArgs{
+		Url:             cluster.OidcIssuerUrl,
+		ClientIdLists:   pulumi.ToStringArray([]string{"sts.amazonaws.com"}),
+		ThumbprintLists: pulumi.ToStringArray([]string{_oidcThumbprint}),
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name": fmt.Sprintf("%s-oidc", cluster.Name),
+			}, tags),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OidcProviderOutput{
+		Arn: provider.Arn,
+		Issuer: provider.Url.ApplyT(func(url string) string {
+			return strings.TrimPrefix(url, "https://")
+		}).(pulumi.StringOutput),
+	}, nil
+}
+
+func CreateServiceAccountRoles(ctx *pulumi.Context, args *ServiceAccountsArgs) (*ServiceAccountsOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	if args.Cluster == nil {
+		return nil, fmt.Errorf("cluster cannot be nil")
+	}
+
+	// defaults are set on copies so the caller's service accounts are left
+	// untouched
+	serviceAccounts := []*ServiceAccount{}
+	names := map[string]bool{}
+	needsOidc := false
+	for _, serviceAccount := range args.ServiceAccounts {
+		serviceAccount := *serviceAccount
+		if serviceAccount.Name == "" {
+			serviceAccount.Name = fmt.Sprintf("%s-%s", serviceAccount.Namespace, serviceAccount.ServiceAccount)
+		}
+		if serviceAccount.Mode == "" {
+			serviceAccount.Mode = ModePodIdentity
+		}
+		if err := validateServiceAccount(&serviceAccount); err != nil {
+			return nil, fmt.Errorf("service account %s: %w", serviceAccount.Name, err)
+		}
+		if names[serviceAccount.Name] {
+			return nil, fmt.Errorf("service account %s is defined more than once", serviceAccount.Name)
+		}
+		names[serviceAccount.Name] = true
+		needsOidc = needsOidc || serviceAccount.Mode == ModeIrsa
+		serviceAccounts = append(serviceAccounts, &serviceAccount)
+	}
+
+	tags := merge(map[string]string{
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	oidcProvider := args.OidcProvider
+	if oidcProvider == nil && needsOidc {
+		var err error
+		oidcProvider, err = CreateOidcProvider(ctx, args.Cluster, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	output := &ServiceAccountsOutput{
+		OidcProvider: oidcProvider,
+		Roles:        map[string]*ServiceAccountRole{},
+	}
+
+	for _, serviceAccount := range serviceAccounts {
+		roleName := serviceAccountRoleName(args.Cluster.Name, serviceAccount.Name)
+
+		var trustPolicy pulumi.StringInput
+		switch serviceAccount.Mode {
+		case ModeIrsa:
+			subject := fmt.Sprintf("system:serviceaccount:%s:%s", serviceAccount.Namespace, serviceAccount.ServiceAccount)
+			trustPolicy = pulumi.All(oidcProvider.Arn, oidcProvider.Issuer).ApplyT(func(values []interface{}) (string, error) {
+				issuer := values[1].(string)
+				return policyDocument([]statement{
+					{
+						Effect: "Allow",
+						Principal: map[string]interface{}{
+							"Federated": values[0].(string),
+						},
+						Action: []string{"sts:AssumeRoleWithWebIdentity"},
+						Condition: map[string]map[string]interface{}{
+							"StringEquals": {
+								fmt.Sprintf("%s:sub", issuer): subject,
+								fmt.Sprintf("%s:aud", issuer): "sts.amazonaws.com",
+							},
+						},
+					},
+				})
+			}).(pulumi.StringOutput)
+		case ModePodIdentity:
+			document, err := policyDocument([]statement{
+				{
+					Effect: "Allow",
+					Principal: map[string]interface{}{
+						"Service": "pods.eks.amazonaws.com",
+					},
+					Action: []string{"sts:AssumeRole", "sts:TagSession"},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			trustPolicy = pulumi.String(document)
+		}
+
+		role, err := iam.NewRole(ctx, roleName, &iam.RoleArgs{
+			Name:             pulumi.String(roleName),
+			AssumeRolePolicy: trustPolicy,
+			Tags: pulumi.ToStringMap(
+				merge(map[string]string{
+					"Name":           roleName,
+					"Namespace":      serviceAccount.Namespace,
+					"ServiceAccount": serviceAccount.ServiceAccount,
+				}, tags),
+			),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for index, policyArn := range serviceAccount.PolicyArns {
+			_, err = iam.NewRolePolicyAttachment(ctx, fmt.Sprintf("%s-%d", roleName, index+1), &iam.RolePolicyAttachmentArgs{
+				Role:      role.Name,
+				PolicyArn: pulumi.String(policyArn),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(serviceAccount.Policy) > 0 {
+			_, err = iam.NewRolePolicy(ctx, roleName, &iam.RolePolicyArgs{
+				Role:   role.Name,
+				Policy: pulumi.String(string(serviceAccount.Policy)),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if serviceAccount.Mode == ModePodIdentity {
+			_, err = eks.NewPodIdentityAssociation(ctx, roleName, &eks.PodIdentityAssociationArgs{
+				ClusterName:    args.Cluster.ClusterName,
+				Namespace:      pulumi.String(serviceAccount.Namespace),
+				ServiceAccount: pulumi.String(serviceAccount.ServiceAccount),
+				RoleArn:        role.Arn,
+				Tags:           pulumi.ToStringMap(tags),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		output.Roles[serviceAccount.Name] = &ServiceAccountRole{
+			RoleArn:  role.Arn,
+			RoleName: role.Name,
+		}
+	}
+
+	return output, nil
+}
+
+// serviceAccountRoleName is <cluster>-<name>. Names over the IAM limit of 64
+// characters are cut and end with a hash of the full name, so they stay
+// unique.
+func serviceAccountRoleName(clusterName string, name string) string {
+	roleName := fmt.Sprintf("%s-%s", clusterName, name)
+	if len(roleName) <= _maxRoleNameLength {
+		return roleName
+	}
+
+	sum := sha256.Sum256([]byte(roleName))
+	suffix := hex.EncodeToString(sum[:4])
+	return fmt.Sprintf("%s-%s", strings.TrimRight(roleName[:_maxRoleNameLength-len(suffix)-1], "-"), suffix)
+}
+
+func validateServiceAccount(serviceAccount *ServiceAccount) error {
+	if serviceAccount.Namespace == "" {
+		return fmt.Errorf("namespace cannot be empty")
+	}
+	if serviceAccount.ServiceAccount == "" {
+		return fmt.Errorf("service account cannot be empty")
+	}
+	if !contains(_serviceAccountModes, serviceAccount.Mode) {
+		return fmt.Errorf("unsupported mode %q", serviceAccount.Mode)
+	}
+	if len(serviceAccount.PolicyArns) == 0 && len(serviceAccount.Policy) == 0 {
+		return fmt.Errorf("at least one policy arn or an inline policy is required")
+	}
+	if len(serviceAccount.Policy) > 0 && !json.Valid(serviceAccount.Policy) {
+		return fmt.Errorf("policy is not valid json")
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package eks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServiceAccountRoleName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name        string
+		clusterName string
+		account     string
+		want        string
+	}{
+		{name: "short", clusterName: "main", account: "kube-system-aws-node", want: "main-kube-system-aws-node"},
+		{name: "exactly at the limit", clusterName: "main", account: strings.Repeat("a", _maxRoleNameLength-5), want: "main-" + strings.Repeat("a", _maxRoleNameLength-5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceAccountRoleName(tt.clusterName, tt.account); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("long names are truncated with a hash", func(t *testing.T) {
+		got := serviceAccountRoleName("main", long)
+		if len(got) > _maxRoleNameLength {
+			t.Errorf("got %d characters, at most %d are allowed", len(got), _maxRoleNameLength)
+		}
+		if !strings.HasPrefix(got, "main-aaaa") {
+			t.Errorf("got %q, want the cluster and account prefix", got)
+		}
+		if got != serviceAccountRoleName("main", long) {
+			t.Error("truncated names are not stable")
+		}
+		if got == serviceAccountRoleName("main", long+"b") {
+			t.Error("names that only differ past the limit share a role name")
+		}
+	})
+
+	t.Run("no dash before the hash", func(t *testing.T) {
+		// the cut lands right after the dash of "a-b"
+		got := serviceAccountRoleName("main", strings.Repeat("a", 49)+"-"+strings.Repeat("b", 20))
+		if strings.Contains(got, "--") {
+			t.Errorf("got %q, want no double dash", got)
+		}
+	})
+}
+
+func TestValidateServiceAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		account ServiceAccount
+		wantErr string
+	}{
+		{
+			name:    "policy arns",
+			account: ServiceAccount{Namespace: "apps", ServiceAccount: "api", Mode: ModePodIdentity, PolicyArns: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"}},
+		},
+		{
+			name:    "inline policy",
+			account: ServiceAccount{Namespace: "apps", ServiceAccount: "api", Mode: ModeIrsa, Policy: json.RawMessage(`{"Version":"2012-10-17","Statement":[]}`)},
+		},
+		{
+			name:    "no namespace",
+			account: ServiceAccount{ServiceAccount: "api", Mode: ModeIrsa, PolicyArns: []string{"arn"}},
+			wantErr: "namespace cannot be empty",
+		},
+		{
+			name:    "no service account",
+			account: ServiceAccount{Namespace: "apps", Mode: ModeIrsa, PolicyArns: []string{"arn"}},
+			wantErr: "service account cannot be empty",
+		},
+		{
+			name:    "unknown mode",
+			account: ServiceAccount{Namespace: "apps", ServiceAccount: "api", Mode: "kiam", PolicyArns: []string{"arn"}},
+			wantErr: "unsupported mode",
+		},
+		{
+			name:    "no policy",
+			account: ServiceAccount{Namespace: "apps", ServiceAccount: "api", Mode: ModeIrsa},
+			wantErr: "at least one policy arn or an inline policy",
+		},
+		{
+			name:    "invalid inline policy",
+			account: ServiceAccount{Namespace: "apps", ServiceAccount: "api", Mode: ModeIrsa, Policy: json.RawMessage(`{`)},
+			wantErr: "policy is not valid json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServiceAccount(&tt.account)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,24 +1,112 @@
 package listenerrule
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	// AWS rejects rules whose conditions carry more than five values in total.
+	MaxConditionValues = 5
+
+	MinPriority = 1
+	MaxPriority = 50000
+
+	// stickiness durations are in seconds, up to seven days
+	MinStickinessDuration = 1
+	MaxStickinessDuration = 604800
+)
+
 type ListenerRuleArgs struct {
-	HostHeaders    []string
-	PathPatterns   []string
-	ListenerArn    string
-	TargetGroupArn string
+	// Name is the logical resource name and must be unique in the stack.
+	Name string
+	// Priority is assigned by AWS when left at zero.
+	Priority int
+	Tags     map[string]string
+
+	HostHeaders  []string
+	PathPatterns []string
+	HttpHeaders  []*HttpHeaderCondition
+	QueryStrings []*QueryStringCondition
+	SourceIps    []string
+	HttpMethods  []string
+
+	// ListenerArn and the target group ARNs take outputs, so rules can be
+	// registered next to the listener and target groups they reference.
+	ListenerArn pulumi.StringInput
+
+	// Exactly one of TargetGroupArn, TargetGroups, Redirect or FixedResponse
+	// must be set.
+	TargetGroupArn pulumi.StringInput
+	TargetGroups   []*WeightedTargetGroup
+	Stickiness     *Stickiness
+	Redirect       *Redirect
+	FixedResponse  *FixedResponse
+}
+
+type HttpHeaderCondition struct {
+	Name   string
+	Values []string
+}
+
+type QueryStringCondition struct {
+	// Key may be empty to match the value against any query parameter.
+	Key   string
+	Value string
+}
+
+type WeightedTargetGroup struct {
+	Arn    pulumi.StringInput
+	Weight int
 }
 
-type ListenerRuleOutput struct{}
+type Stickiness struct {
+	Enabled bool
+	// Duration is in seconds, between 1 and 604800.
+	Duration int
+}
+
+type Redirect struct {
+	Host     string `json:"host"`
+	Path     string `json:"path"`
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+	Query    string `json:"query"`
+	// StatusCode is HTTP_301 or HTTP_302, defaulting to HTTP_301.
+	StatusCode string `json:"status_code"`
+}
+
+type FixedResponse struct {
+	ContentType string `json:"content_type"`
+	MessageBody string `json:"message_body"`
+	// StatusCode is required and must be a 2XX, 4XX or 5XX code.
+	StatusCode string `json:"status_code"`
+}
+
+type ListenerRuleOutput struct {
+	ListenerRuleArn pulumi.StringOutput
+	ListenerRuleId  pulumi.IDOutput
+	// ListenerRule lets dependents wait for the rule, e.g. ECS services whose
+	// target group must be attached to a load balancer first.
+	ListenerRule *lb.ListenerRule
+}
 
 func CreateListenerRule(ctx *pulumi.Context, args ListenerRuleArgs) (*ListenerRuleOutput, error) {
-	if len(args.PathPatterns) == 0 {
+	if args.Name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+
+	if len(args.PathPatterns) == 0 && !hasConditions(args) {
 		args.PathPatterns = []string{"/"}
 	}
 
+	if err := Validate(args); err != nil {
+		return nil, fmt.Errorf("listener rule %s: %w", args.Name, err)
+	}
+
 	conditions := lb.ListenerRuleConditionArray{}
 	if len(args.HostHeaders) > 0 {
 		conditions = append(conditions, lb.ListenerRuleConditionArgs{
@@ -34,20 +122,225 @@ func CreateListenerRule(ctx *pulumi.Context, args ListenerRuleArgs) (*ListenerRu
 			},
 		})
 	}
+	for _, header := range args.HttpHeaders {
+		conditions = append(conditions, lb.ListenerRuleConditionArgs{
+			HttpHeader: lb.ListenerRuleConditionHttpHeaderArgs{
+				HttpHeaderName: pulumi.String(header.Name),
+				Values:         pulumi.ToStringArray(header.Values),
+			},
+		})
+	}
+	if len(args.QueryStrings) > 0 {
+		queryStrings := lb.ListenerRuleConditionQueryStringArray{}
+		for _, queryString := range args.QueryStrings {
+			queryStrings = append(queryStrings, lb.ListenerRuleConditionQueryStringArgs{
+				Key:   stringPtr(queryString.Key),
+				Value: pulumi.String(queryString.Value),
+			})
+		}
+		conditions = append(conditions, lb.ListenerRuleConditionArgs{
+			QueryStrings: queryStrings,
+		})
+	}
+	if len(args.SourceIps) > 0 {
+		conditions = append(conditions, lb.ListenerRuleConditionArgs{
+			SourceIp: lb.ListenerRuleConditionSourceIpArgs{
+				Values: pulumi.ToStringArray(args.SourceIps),
+			},
+		})
+	}
+	if len(args.HttpMethods) > 0 {
+		conditions = append(conditions, lb.ListenerRuleConditionArgs{
+			HttpRequestMethod: lb.ListenerRuleConditionHttpRequestMethodArgs{
+				Values: pulumi.ToStringArray(args.HttpMethods),
+			},
+		})
+	}
+
+	var priority pulumi.IntPtrInput
+	if args.Priority > 0 {
+		priority = pulumi.IntPtr(args.Priority)
+	}
 
-	_, err := lb.NewListenerRule(ctx, "listener-rule", &lb.ListenerRuleArgs{
-		ListenerArn: pulumi.String(args.ListenerArn),
+	rule, err := lb.NewListenerRule(ctx, args.Name, &lb.ListenerRuleArgs{
+		ListenerArn: args.ListenerArn,
+		Priority:    priority,
 		Actions: lb.ListenerRuleActionArray{
-			lb.ListenerRuleActionArgs{
-				Type:           pulumi.String("forward"),
-				TargetGroupArn: pulumi.String(args.TargetGroupArn),
-			},
+			action(args),
 		},
 		Conditions: conditions,
+		Tags:       pulumi.ToStringMap(args.Tags),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &ListenerRuleOutput{}, nil
+	return &ListenerRuleOutput{
+		ListenerRuleArn: rule.Arn,
+		ListenerRuleId:  rule.ID(),
+		ListenerRule:    rule,
+	}, nil
+}
+
+// Validate checks a rule against the ELB limits without creating anything,
+// so callers building many rules can fail before the first one is created.
+func Validate(args ListenerRuleArgs) error {
+	if args.ListenerArn == nil {
+		return fmt.Errorf("listener arn cannot be empty")
+	}
+
+	if args.Priority != 0 && (args.Priority < MinPriority || args.Priority > MaxPriority) {
+		return fmt.Errorf("priority must be between %d and %d, got %d", MinPriority, MaxPriority, args.Priority)
+	}
+
+	if n := ConditionValueCount(args); n > MaxConditionValues {
+		return fmt.Errorf("conditions have %d values, at most %d are allowed", n, MaxConditionValues)
+	}
+
+	actions := 0
+	if args.TargetGroupArn != nil {
+		actions++
+	}
+	if len(args.TargetGroups) > 0 {
+		actions++
+	}
+	if args.Redirect != nil {
+		actions++
+	}
+	if args.FixedResponse != nil {
+		actions++
+	}
+	if actions != 1 {
+		return fmt.Errorf("exactly one of target group, weighted target groups, redirect or fixed response must be set")
+	}
+
+	if len(args.TargetGroups) > 5 {
+		return fmt.Errorf("a forward action supports at most 5 target groups, got %d", len(args.TargetGroups))
+	}
+	for _, targetGroup := range args.TargetGroups {
+		if targetGroup.Arn == nil {
+			return fmt.Errorf("weighted target group arn cannot be empty")
+		}
+		if targetGroup.Weight < 0 || targetGroup.Weight > 999 {
+			return fmt.Errorf("target group weight must be between 0 and 999, got %d", targetGroup.Weight)
+		}
+	}
+	if len(args.TargetGroups) > 0 && args.Stickiness != nil {
+		if args.Stickiness.Duration < MinStickinessDuration || args.Stickiness.Duration > MaxStickinessDuration {
+			return fmt.Errorf("stickiness duration must be between %d and %d seconds, got %d", MinStickinessDuration, MaxStickinessDuration, args.Stickiness.Duration)
+		}
+	}
+
+	if args.FixedResponse != nil && !validFixedResponseStatusCode(args.FixedResponse.StatusCode) {
+		return fmt.Errorf("fixed response status code must be a 2XX, 4XX or 5XX code, got %q", args.FixedResponse.StatusCode)
+	}
+
+	for _, header := range args.HttpHeaders {
+		if header.Name == "" {
+			return fmt.Errorf("http header condition name cannot be empty")
+		}
+		if len(header.Values) == 0 {
+			return fmt.Errorf("http header condition %s needs at least one value", header.Name)
+		}
+	}
+
+	return nil
+}
+
+// ConditionValueCount returns the number of values AWS counts towards the
+// per-rule condition limit.
+func ConditionValueCount(args ListenerRuleArgs) int {
+	count := len(args.HostHeaders) + len(args.PathPatterns) + len(args.SourceIps) + len(args.HttpMethods) + len(args.QueryStrings)
+	for _, header := range args.HttpHeaders {
+		count += len(header.Values)
+	}
+
+	return count
+}
+
+func validFixedResponseStatusCode(statusCode string) bool {
+	if len(statusCode) != 3 || !strings.ContainsRune("245", rune(statusCode[0])) {
+		return false
+	}
+	for _, digit := range statusCode[1:] {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasConditions(args ListenerRuleArgs) bool {
+	return len(args.HostHeaders) > 0 ||
+		len(args.HttpHeaders) > 0 ||
+		len(args.QueryStrings) > 0 ||
+		len(args.SourceIps) > 0 ||
+		len(args.HttpMethods) > 0
+}
+
+func action(args ListenerRuleArgs) lb.ListenerRuleActionArgs {
+	switch {
+	case args.Redirect != nil:
+		statusCode := args.Redirect.StatusCode
+		if statusCode == "" {
+			statusCode = "HTTP_301"
+		}
+		return lb.ListenerRuleActionArgs{
+			Type: pulumi.String("redirect"),
+			Redirect: lb.ListenerRuleActionRedirectArgs{
+				Host:       stringPtr(args.Redirect.Host),
+				Path:       stringPtr(args.Redirect.Path),
+				Port:       stringPtr(args.Redirect.Port),
+				Protocol:   stringPtr(args.Redirect.Protocol),
+				Query:      stringPtr(args.Redirect.Query),
+				StatusCode: pulumi.String(statusCode),
+			},
+		}
+	case args.FixedResponse != nil:
+		return lb.ListenerRuleActionArgs{
+			Type: pulumi.String("fixed-response"),
+			FixedResponse: lb.ListenerRuleActionFixedResponseArgs{
+				ContentType: pulumi.String(args.FixedResponse.ContentType),
+				MessageBody: stringPtr(args.FixedResponse.MessageBody),
+				StatusCode:  stringPtr(args.FixedResponse.StatusCode),
+			},
+		}
+	case len(args.TargetGroups) > 0:
+		targetGroups := lb.ListenerRuleActionForwardTargetGroupArray{}
+		for _, targetGroup := range args.TargetGroups {
+			targetGroups = append(targetGroups, lb.ListenerRuleActionForwardTargetGroupArgs{
+				Arn:    targetGroup.Arn,
+				Weight: pulumi.IntPtr(targetGroup.Weight),
+			})
+		}
+
+		forward := lb.ListenerRuleActionForwardArgs{
+			TargetGroups: targetGroups,
+		}
+		if args.Stickiness != nil {
+			forward.Stickiness = lb.ListenerRuleActionForwardStickinessArgs{
+				Enabled:  pulumi.BoolPtr(args.Stickiness.Enabled),
+				Duration: pulumi.Int(args.Stickiness.Duration),
+			}
+		}
+
+		return lb.ListenerRuleActionArgs{
+			Type:    pulumi.String("forward"),
+			Forward: forward,
+		}
+	default:
+		return lb.ListenerRuleActionArgs{
+			Type:           pulumi.String("forward"),
+			TargetGroupArn: args.TargetGroupArn,
+		}
+	}
+}
+
+func stringPtr(value string) pulumi.StringPtrInput {
+	if value == "" {
+		return nil
+	}
+
+	return pulumi.StringPtr(value)
 }
@@ -0,0 +1,153 @@
+package listenerrule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestValidate(t *testing.T) {
+	listenerArn := pulumi.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/main/1/1")
+	targetGroupArn := pulumi.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/web/1")
+
+	tests := []struct {
+		name    string
+		args    ListenerRuleArgs
+		wantErr string
+	}{
+		{
+			name: "forward",
+			args: ListenerRuleArgs{ListenerArn: listenerArn, Priority: 10, HostHeaders: []string{"example.com"}, TargetGroupArn: targetGroupArn},
+		},
+		{
+			name:    "missing listener",
+			args:    ListenerRuleArgs{TargetGroupArn: targetGroupArn},
+			wantErr: "listener arn cannot be empty",
+		},
+		{
+			name:    "priority out of range",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn, Priority: MaxPriority + 1, TargetGroupArn: targetGroupArn},
+			wantErr: "priority must be between",
+		},
+		{
+			name: "too many condition values",
+			args: ListenerRuleArgs{
+				ListenerArn:    listenerArn,
+				HostHeaders:    []string{"a", "b", "c"},
+				HttpHeaders:    []*HttpHeaderCondition{{Name: "X-Env", Values: []string{"a", "b", "c"}}},
+				TargetGroupArn: targetGroupArn,
+			},
+			wantErr: "at most 5 are allowed",
+		},
+		{
+			name:    "no action",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn},
+			wantErr: "exactly one of",
+		},
+		{
+			name:    "two actions",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn, TargetGroupArn: targetGroupArn, Redirect: &Redirect{Path: "/"}},
+			wantErr: "exactly one of",
+		},
+		{
+			name: "weighted forward with stickiness",
+			args: ListenerRuleArgs{
+				ListenerArn:  listenerArn,
+				TargetGroups: []*WeightedTargetGroup{{Arn: targetGroupArn, Weight: 90}, {Arn: targetGroupArn, Weight: 10}},
+				Stickiness:   &Stickiness{Enabled: true, Duration: 3600},
+			},
+		},
+		{
+			name: "weight out of range",
+			args: ListenerRuleArgs{
+				ListenerArn:  listenerArn,
+				TargetGroups: []*WeightedTargetGroup{{Arn: targetGroupArn, Weight: 1000}},
+			},
+			wantErr: "weight must be between 0 and 999",
+		},
+		{
+			name: "stickiness without duration",
+			args: ListenerRuleArgs{
+				ListenerArn:  listenerArn,
+				TargetGroups: []*WeightedTargetGroup{{Arn: targetGroupArn, Weight: 1}},
+				Stickiness:   &Stickiness{Enabled: true},
+			},
+			wantErr: "stickiness duration must be between 1 and 604800",
+		},
+		{
+			name: "stickiness longer than seven days",
+			args: ListenerRuleArgs{
+				ListenerArn:  listenerArn,
+				TargetGroups: []*WeightedTargetGroup{{Arn: targetGroupArn, Weight: 1}},
+				Stickiness:   &Stickiness{Enabled: true, Duration: MaxStickinessDuration + 1},
+			},
+			wantErr: "stickiness duration must be between 1 and 604800",
+		},
+		{
+			name: "fixed response",
+			args: ListenerRuleArgs{ListenerArn: listenerArn, FixedResponse: &FixedResponse{ContentType: "text/plain", StatusCode: "503"}},
+		},
+		{
+			name:    "fixed response without status code",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn, FixedResponse: &FixedResponse{ContentType: "text/plain"}},
+			wantErr: "fixed response status code",
+		},
+		{
+			name:    "fixed response with a 3XX status code",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn, FixedResponse: &FixedResponse{ContentType: "text/plain", StatusCode: "301"}},
+			wantErr: "fixed response status code",
+		},
+		{
+			name:    "fixed response with a malformed status code",
+			args:    ListenerRuleArgs{ListenerArn: listenerArn, FixedResponse: &FixedResponse{ContentType: "text/plain", StatusCode: "4xx"}},
+			wantErr: "fixed response status code",
+		},
+		{
+			name: "http header without name",
+			args: ListenerRuleArgs{
+				ListenerArn:    listenerArn,
+				HttpHeaders:    []*HttpHeaderCondition{{Values: []string{"a"}}},
+				TargetGroupArn: targetGroupArn,
+			},
+			wantErr: "name cannot be empty",
+		},
+		{
+			name: "http header without values",
+			args: ListenerRuleArgs{
+				ListenerArn:    listenerArn,
+				HttpHeaders:    []*HttpHeaderCondition{{Name: "X-Env"}},
+				TargetGroupArn: targetGroupArn,
+			},
+			wantErr: "needs at least one value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionValueCount(t *testing.T) {
+	args := ListenerRuleArgs{
+		HostHeaders:  []string{"example.com"},
+		PathPatterns: []string{"/api/*", "/v1/*"},
+		HttpHeaders:  []*HttpHeaderCondition{{Name: "X-Env", Values: []string{"dev", "test"}}},
+		QueryStrings: []*QueryStringCondition{{Key: "debug", Value: "1"}},
+	}
+
+	if got := ConditionValueCount(args); got != 6 {
+		t.Errorf("got %d condition values, want 6", got)
+	}
+}
@@ -0,0 +1,186 @@
+package routing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	listenerrule "github.com/tungnt76/pulumi-in-go/aws/elb/alb/listener_rule"
+)
+
+const (
+	// AWS allows 100 rules per listener, not counting the default action.
+	_maxRules = 100
+
+	// automatic priorities are taken from [_autoPriorityMin, MaxPriority],
+	// explicit ones from below it, so pinned routes are evaluated first.
+	_autoPriorityMin = 10000
+)
+
+// Route is one entry of the routing table. Exactly one of TargetGroup,
+// Redirect or FixedResponse is set.
+type Route struct {
+	Name string `json:"name"`
+	// Priority pins the evaluation order, lowest first, and must be below
+	// 10000. When zero it is derived from a hash of Name, so adding,
+	// removing or reordering routes does not renumber the others. Routes
+	// that can match the same request need an explicit priority.
+	Priority      int                         `json:"priority"`
+	Hosts         []string                    `json:"hosts"`
+	Paths         []string                    `json:"paths"`
+	TargetGroup   string                      `json:"target_group"`
+	Redirect      *listenerrule.Redirect      `json:"redirect"`
+	FixedResponse *listenerrule.FixedResponse `json:"fixed_response"`
+}
+
+type RoutingTableArgs struct {
+	// Name prefixes the logical name of every listener rule.
+	Name        string
+	ListenerArn pulumi.StringInput
+	// TargetGroupArns resolves Route.TargetGroup to a target group ARN.
+	TargetGroupArns map[string]pulumi.StringInput
+	Routes          []*Route
+	Tags            map[string]string
+}
+
+type RoutingTableOutput struct {
+	ListenerRules map[string]*listenerrule.ListenerRuleOutput
+	Priorities    map[string]int
+}
+
+// RoutesFromConfig reads the routing table from the `routes` key of the given
+// config namespace.
+func RoutesFromConfig(ctx *pulumi.Context, namespace string) ([]*Route, error) {
+	routes := []*Route{}
+	if err := config.New(ctx, namespace).GetObject("routes", &routes); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+func CreateRoutingTable(ctx *pulumi.Context, args *RoutingTableArgs) (*RoutingTableOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	rules, err := listenerRules(args)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &RoutingTableOutput{
+		ListenerRules: map[string]*listenerrule.ListenerRuleOutput{},
+		Priorities:    map[string]int{},
+	}
+	for index, rule := range rules {
+		ruleOutput, err := listenerrule.CreateListenerRule(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+
+		output.ListenerRules[args.Routes[index].Name] = ruleOutput
+		output.Priorities[args.Routes[index].Name] = rule.Priority
+	}
+
+	return output, nil
+}
+
+// listenerRules turns the routes into listener rule args and validates all of
+// them before any resource is registered.
+func listenerRules(args *RoutingTableArgs) ([]listenerrule.ListenerRuleArgs, error) {
+	if len(args.Routes) > _maxRules {
+		return nil, fmt.Errorf("routing table %s has %d routes, at most %d are allowed per listener", args.Name, len(args.Routes), _maxRules)
+	}
+
+	priorities, err := assignPriorities(args.Routes)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	rules := []listenerrule.ListenerRuleArgs{}
+	for index, route := range args.Routes {
+		if route.Name == "" {
+			return nil, fmt.Errorf("route %d has no name", index+1)
+		}
+		if seen[route.Name] {
+			return nil, fmt.Errorf("duplicate route name %q", route.Name)
+		}
+		seen[route.Name] = true
+
+		if len(route.Hosts) == 0 && len(route.Paths) == 0 {
+			return nil, fmt.Errorf("route %s must match on at least one host or path", route.Name)
+		}
+
+		rule := listenerrule.ListenerRuleArgs{
+			Name:          fmt.Sprintf("%s-%s", args.Name, route.Name),
+			Priority:      priorities[route.Name],
+			Tags:          args.Tags,
+			HostHeaders:   route.Hosts,
+			PathPatterns:  route.Paths,
+			ListenerArn:   args.ListenerArn,
+			Redirect:      route.Redirect,
+			FixedResponse: route.FixedResponse,
+		}
+		if route.TargetGroup != "" {
+			arn, ok := args.TargetGroupArns[route.TargetGroup]
+			if !ok {
+				return nil, fmt.Errorf("route %s references unknown target group %q", route.Name, route.TargetGroup)
+			}
+			rule.TargetGroupArn = arn
+		}
+
+		if err := listenerrule.Validate(rule); err != nil {
+			return nil, fmt.Errorf("route %s: %w", route.Name, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// assignPriorities keeps explicit priorities and gives every other route the
+// first free priority at or after the hash of its name. Routes are probed in
+// name order, so a route only moves when a new name collides with it.
+func assignPriorities(routes []*Route) (map[string]int, error) {
+	priorities := map[string]int{}
+	taken := map[int]string{}
+	automatic := []string{}
+	for _, route := range routes {
+		if route.Priority == 0 {
+			automatic = append(automatic, route.Name)
+			continue
+		}
+		if route.Priority < listenerrule.MinPriority || route.Priority >= _autoPriorityMin {
+			return nil, fmt.Errorf("route %s has priority %d, explicit priorities must be between %d and %d", route.Name, route.Priority, listenerrule.MinPriority, _autoPriorityMin-1)
+		}
+		if other, ok := taken[route.Priority]; ok {
+			return nil, fmt.Errorf("routes %s and %s share priority %d", other, route.Name, route.Priority)
+		}
+		taken[route.Priority] = route.Name
+		priorities[route.Name] = route.Priority
+	}
+
+	sort.Strings(automatic)
+	band := listenerrule.MaxPriority - _autoPriorityMin + 1
+	for _, name := range automatic {
+		hash := fnv.New32a()
+		hash.Write([]byte(name))
+		offset := int(hash.Sum32() % uint32(band))
+
+		priority := _autoPriorityMin + offset
+		for taken[priority] != "" {
+			offset = (offset + 1) % band
+			priority = _autoPriorityMin + offset
+		}
+		taken[priority] = name
+		priorities[name] = priority
+	}
+
+	return priorities, nil
+}
@@ -0,0 +1,211 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	listenerrule "github.com/tungnt76/pulumi-in-go/aws/elb/alb/listener_rule"
+)
+
+func TestAssignPriorities(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  []*Route
+		pinned  map[string]int
+		wantErr string
+	}{
+		{
+			name:   "automatic",
+			routes: []*Route{{Name: "api"}, {Name: "web"}, {Name: "docs"}},
+		},
+		{
+			name:   "explicit override",
+			routes: []*Route{{Name: "api", Priority: 5}, {Name: "web"}},
+			pinned: map[string]int{"api": 5},
+		},
+		{
+			name:    "duplicate explicit priority",
+			routes:  []*Route{{Name: "api", Priority: 5}, {Name: "web", Priority: 5}},
+			wantErr: "share priority 5",
+		},
+		{
+			name:    "explicit priority in the automatic band",
+			routes:  []*Route{{Name: "api", Priority: _autoPriorityMin}},
+			wantErr: "explicit priorities must be between",
+		},
+		{
+			name:    "negative priority",
+			routes:  []*Route{{Name: "api", Priority: -1}},
+			wantErr: "explicit priorities must be between",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			priorities, err := assignPriorities(tt.routes)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			seen := map[int]string{}
+			for _, route := range tt.routes {
+				priority := priorities[route.Name]
+				if want, ok := tt.pinned[route.Name]; ok {
+					if priority != want {
+						t.Errorf("route %s got priority %d, want %d", route.Name, priority, want)
+					}
+				} else if priority < _autoPriorityMin || priority > listenerrule.MaxPriority {
+					t.Errorf("route %s got automatic priority %d outside [%d, %d]", route.Name, priority, _autoPriorityMin, listenerrule.MaxPriority)
+				}
+				if other, ok := seen[priority]; ok {
+					t.Errorf("routes %s and %s share priority %d", other, route.Name, priority)
+				}
+				seen[priority] = route.Name
+			}
+		})
+	}
+}
+
+func TestAssignPrioritiesIsStable(t *testing.T) {
+	before, err := assignPriorities([]*Route{{Name: "api"}, {Name: "web"}, {Name: "docs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// reordered, with a route added in front and one pinned
+	after, err := assignPriorities([]*Route{{Name: "admin"}, {Name: "docs"}, {Name: "health", Priority: 1}, {Name: "web"}, {Name: "api"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, priority := range before {
+		if after[name] != priority {
+			t.Errorf("route %s moved from %d to %d", name, priority, after[name])
+		}
+	}
+}
+
+func TestAssignPrioritiesProbesCollisions(t *testing.T) {
+	routes := []*Route{}
+	for i := 0; i < _maxRules; i++ {
+		routes = append(routes, &Route{Name: strings.Repeat("r", i+1)})
+	}
+
+	priorities, err := assignPriorities(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[int]bool{}
+	for _, priority := range priorities {
+		if seen[priority] {
+			t.Fatalf("priority %d assigned twice", priority)
+		}
+		seen[priority] = true
+	}
+}
+
+func TestListenerRules(t *testing.T) {
+	targetGroupArns := map[string]pulumi.StringInput{
+		"web": pulumi.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/web/1"),
+	}
+
+	tests := []struct {
+		name    string
+		routes  []*Route
+		wantErr string
+	}{
+		{
+			name: "valid",
+			routes: []*Route{
+				{Name: "web", Hosts: []string{"example.com"}, TargetGroup: "web"},
+				{Name: "old", Paths: []string{"/old/*"}, Redirect: &listenerrule.Redirect{Path: "/new"}},
+			},
+		},
+		{
+			name:    "missing name",
+			routes:  []*Route{{Hosts: []string{"example.com"}, TargetGroup: "web"}},
+			wantErr: "has no name",
+		},
+		{
+			name: "duplicate name",
+			routes: []*Route{
+				{Name: "web", Hosts: []string{"a.example.com"}, TargetGroup: "web"},
+				{Name: "web", Hosts: []string{"b.example.com"}, TargetGroup: "web"},
+			},
+			wantErr: "duplicate route name",
+		},
+		{
+			name:    "no conditions",
+			routes:  []*Route{{Name: "web", TargetGroup: "web"}},
+			wantErr: "at least one host or path",
+		},
+		{
+			name:    "unknown target group",
+			routes:  []*Route{{Name: "web", Hosts: []string{"example.com"}, TargetGroup: "api"}},
+			wantErr: "unknown target group",
+		},
+		{
+			name: "too many condition values",
+			routes: []*Route{
+				{Name: "web", Hosts: []string{"a", "b", "c"}, Paths: []string{"/a", "/b", "/c"}, TargetGroup: "web"},
+			},
+			wantErr: "at most 5 are allowed",
+		},
+		{
+			name: "no action",
+			routes: []*Route{
+				{Name: "web", Hosts: []string{"example.com"}},
+			},
+			wantErr: "exactly one of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := listenerRules(&RoutingTableArgs{
+				Name:            "main",
+				ListenerArn:     pulumi.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:listener/app/main/1/1"),
+				TargetGroupArns: targetGroupArns,
+				Routes:          tt.routes,
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rules) != len(tt.routes) {
+				t.Fatalf("got %d rules, want %d", len(rules), len(tt.routes))
+			}
+			for _, rule := range rules {
+				if rule.Priority == 0 {
+					t.Errorf("rule %s has no priority", rule.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestListenerRulesTooManyRoutes(t *testing.T) {
+	routes := []*Route{}
+	for i := 0; i <= _maxRules; i++ {
+		routes = append(routes, &Route{Name: strings.Repeat("r", i+1), Paths: []string{"/"}, TargetGroup: "web"})
+	}
+
+	_, err := listenerRules(&RoutingTableArgs{Name: "main", Routes: routes})
+	if err == nil || !strings.Contains(err.Error(), "at most 100") {
+		t.Fatalf("got error %v, want the rule limit", err)
+	}
+}
@@ -0,0 +1,134 @@
+package bluegreen
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	listenerrule "github.com/tungnt76/pulumi-in-go/aws/elb/alb/listener_rule"
+	targetgroup "github.com/tungnt76/pulumi-in-go/aws/elb/target-group"
+)
+
+const _maxWeight = 999
+
+// Weights splits traffic between the blue and green target groups. Only the
+// ratio matters, so 90/10 and 9/1 route the same way.
+type Weights struct {
+	Blue  int `json:"blue"`
+	Green int `json:"green"`
+}
+
+type BlueGreenArgs struct {
+	Name string
+	// TargetGroup is the template for both target groups; its Name is
+	// replaced with <Name>-blue and <Name>-green.
+	TargetGroup targetgroup.TargetGroupArgs
+	// Rule, when set, forwards the requests matching its conditions through
+	// a listener rule. Its action fields are ignored. Without a rule the
+	// caller wires WeightedTargetGroups into the listener default action.
+	Rule       *listenerrule.ListenerRuleArgs
+	Weights    *Weights
+	Stickiness *listenerrule.Stickiness
+}
+
+type BlueGreenOutput struct {
+	Blue            *targetgroup.TargetGroupOutput
+	Green           *targetgroup.TargetGroupOutput
+	Weights         Weights
+	Stickiness      *listenerrule.Stickiness
+	ListenerRuleArn pulumi.StringOutput
+}
+
+// WeightsFromConfig reads the `weights` object of the given config namespace,
+// e.g. `deployment:weights: {blue: 90, green: 10}`. Traffic stays on blue when
+// the key is missing.
+func WeightsFromConfig(ctx *pulumi.Context, namespace string) (*Weights, error) {
+	weights := &Weights{Blue: 100}
+	if err := config.New(ctx, namespace).GetObject("weights", weights); err != nil {
+		return nil, err
+	}
+
+	return weights, nil
+}
+
+func CreateBlueGreen(ctx *pulumi.Context, args *BlueGreenArgs) (*BlueGreenOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	weights := Weights{Blue: 100}
+	if args.Weights != nil {
+		weights = *args.Weights
+	}
+	if err := validateWeights(weights); err != nil {
+		return nil, err
+	}
+
+	// the duration is required by the API even when stickiness is disabled
+	if args.Stickiness != nil && (args.Stickiness.Duration < 1 || args.Stickiness.Duration > 604800) {
+		return nil, fmt.Errorf("stickiness duration must be between 1 and 604800 seconds, got %d", args.Stickiness.Duration)
+	}
+
+	blueArgs := args.TargetGroup
+	blueArgs.Name = fmt.Sprintf("%s-blue", args.Name)
+	blue, err := targetgroup.CreateTargetGroup(ctx, &blueArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	greenArgs := args.TargetGroup
+	greenArgs.Name = fmt.Sprintf("%s-green", args.Name)
+	green, err := targetgroup.CreateTargetGroup(ctx, &greenArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &BlueGreenOutput{
+		Blue:       blue,
+		Green:      green,
+		Weights:    weights,
+		Stickiness: args.Stickiness,
+	}
+
+	if args.Rule != nil {
+		rule := *args.Rule
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s-rule", args.Name)
+		}
+
+		rule.TargetGroupArn = nil
+		rule.Redirect = nil
+		rule.FixedResponse = nil
+		rule.TargetGroups = output.WeightedTargetGroups()
+		rule.Stickiness = args.Stickiness
+
+		ruleOutput, err := listenerrule.CreateListenerRule(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+		output.ListenerRuleArn = ruleOutput.ListenerRuleArn
+	}
+
+	return output, nil
+}
+
+// WeightedTargetGroups pairs the blue and green ARNs with the configured
+// weights, ready for a forward action.
+func (o *BlueGreenOutput) WeightedTargetGroups() []*listenerrule.WeightedTargetGroup {
+	return []*listenerrule.WeightedTargetGroup{
+		{Arn: o.Blue.TargetGroupArn, Weight: o.Weights.Blue},
+		{Arn: o.Green.TargetGroupArn, Weight: o.Weights.Green},
+	}
+}
+
+func validateWeights(weights Weights) error {
+	if weights.Blue < 0 || weights.Blue > _maxWeight || weights.Green < 0 || weights.Green > _maxWeight {
+		return fmt.Errorf("weights must be between 0 and %d, got blue=%d green=%d", _maxWeight, weights.Blue, weights.Green)
+	}
+	if weights.Blue+weights.Green == 0 {
+		return fmt.Errorf("at least one of the blue and green weights must be positive")
+	}
+
+	return nil
+}
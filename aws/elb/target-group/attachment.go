@@ -0,0 +1,246 @@
+package targetgroup
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lambda"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+type AttachmentArgs struct {
+	// Name prefixes the logical name of every attachment.
+	Name string
+	// TargetGroupArn, the target ids and LambdaArns take outputs, so targets
+	// can be attached to resources created in the same program.
+	TargetGroupArn pulumi.StringInput
+	// Port is used for instance and IP targets that do not set their own.
+	Port int
+
+	Instances []*Target
+	Ips       []*Target
+	// Vpc is the VPC the target group lives in. Known IP targets must fall
+	// inside its CIDR unless AllowIpsOutsideVpc is set, e.g. for peered
+	// networks.
+	Vpc                *vpc.VpcArgs
+	AllowIpsOutsideVpc bool
+
+	LambdaArns []pulumi.StringInput
+}
+
+type Target struct {
+	Id   pulumi.StringInput
+	Port int
+	// Name replaces the id in the logical name. Targets whose id is an
+	// output and that have no name are numbered in the order given.
+	Name string
+}
+
+type AttachmentOutput struct {
+	Attachments []*lb.TargetGroupAttachment
+	Permissions []*lambda.Permission
+}
+
+func AttachTargets(ctx *pulumi.Context, args *AttachmentArgs) (*AttachmentOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	if err := validateAttachment(args); err != nil {
+		return nil, fmt.Errorf("target group attachment %s: %w", args.Name, err)
+	}
+
+	output := &AttachmentOutput{}
+
+	for index, instance := range args.Instances {
+		attachment, err := lb.NewTargetGroupAttachment(ctx, targetName(args.Name, instance, index, targetPort(instance, args.Port)), &lb.TargetGroupAttachmentArgs{
+			TargetGroupArn: args.TargetGroupArn,
+			TargetId:       instance.Id,
+			Port:           pulumi.IntPtr(targetPort(instance, args.Port)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		output.Attachments = append(output.Attachments, attachment)
+	}
+
+	var vpcNetwork *net.IPNet
+	if args.Vpc != nil {
+		_, vpcNetwork, _ = net.ParseCIDR(args.Vpc.Cidr)
+	}
+	for index, ip := range args.Ips {
+		// targets outside the VPC must be registered in every zone
+		availabilityZone := ip.Id.ToStringOutput().ApplyT(func(id string) *string {
+			if vpcNetwork != nil && vpcNetwork.Contains(net.ParseIP(id)) {
+				return nil
+			}
+			all := "all"
+			return &all
+		}).(pulumi.StringPtrOutput)
+
+		attachment, err := lb.NewTargetGroupAttachment(ctx, targetName(args.Name, ip, index, targetPort(ip, args.Port)), &lb.TargetGroupAttachmentArgs{
+			TargetGroupArn:   args.TargetGroupArn,
+			TargetId:         ip.Id,
+			Port:             pulumi.IntPtr(targetPort(ip, args.Port)),
+			AvailabilityZone: availabilityZone,
+		})
+		if err != nil {
+			return nil, err
+		}
+		output.Attachments = append(output.Attachments, attachment)
+	}
+
+	for index, lambdaArn := range args.LambdaArns {
+		name := fmt.Sprintf("%s-lambda-%d", args.Name, index+1)
+		if arn, ok := knownString(lambdaArn); ok {
+			name = fmt.Sprintf("%s-%s", args.Name, lambdaFunctionName(arn))
+		}
+
+		permission, err := lambda.NewPermission(ctx, name, &lambda.PermissionArgs{
+			Action:    pulumi.String("lambda:InvokeFunction"),
+			Function:  lambdaArn,
+			Principal: pulumi.String("elasticloadbalancing.amazonaws.com"),
+			SourceArn: args.TargetGroupArn,
+		})
+		if err != nil {
+			return nil, err
+		}
+		output.Permissions = append(output.Permissions, permission)
+
+		attachment, err := lb.NewTargetGroupAttachment(ctx, name, &lb.TargetGroupAttachmentArgs{
+			TargetGroupArn: args.TargetGroupArn,
+			TargetId:       lambdaArn,
+		}, pulumi.DependsOn([]pulumi.Resource{
+			permission,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		output.Attachments = append(output.Attachments, attachment)
+	}
+
+	return output, nil
+}
+
+// validateAttachment checks the targets whose values are known; outputs are
+// left to the ELB API.
+func validateAttachment(args *AttachmentArgs) error {
+	if args.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if args.TargetGroupArn == nil {
+		return fmt.Errorf("target group arn cannot be empty")
+	}
+
+	for _, target := range append(append([]*Target{}, args.Instances...), args.Ips...) {
+		if target.Id == nil {
+			return fmt.Errorf("target id cannot be empty")
+		}
+		if port := targetPort(target, args.Port); port < 1 || port > 65535 {
+			return fmt.Errorf("target %s: port must be between 1 and 65535, got %d", targetLabel(target), port)
+		}
+	}
+
+	for _, instance := range args.Instances {
+		if id, ok := knownString(instance.Id); ok && !strings.HasPrefix(id, "i-") {
+			return fmt.Errorf("%q is not an instance id", id)
+		}
+	}
+
+	if len(args.Ips) > 0 {
+		if args.Vpc == nil && !args.AllowIpsOutsideVpc {
+			return fmt.Errorf("ip targets need the vpc to validate against")
+		}
+
+		var vpcNetwork *net.IPNet
+		if args.Vpc != nil {
+			_, network, err := net.ParseCIDR(args.Vpc.Cidr)
+			if err != nil {
+				return fmt.Errorf("vpc %s: %w", args.Vpc.Name, err)
+			}
+			vpcNetwork = network
+		}
+
+		for _, target := range args.Ips {
+			id, ok := knownString(target.Id)
+			if !ok {
+				continue
+			}
+			ip := net.ParseIP(id)
+			if ip == nil {
+				return fmt.Errorf("%q is not an ip address", id)
+			}
+			if vpcNetwork != nil && !vpcNetwork.Contains(ip) && !args.AllowIpsOutsideVpc {
+				return fmt.Errorf("ip %s is outside the vpc cidr %s", id, args.Vpc.Cidr)
+			}
+		}
+	}
+
+	for _, lambdaArn := range args.LambdaArns {
+		if lambdaArn == nil {
+			return fmt.Errorf("lambda arn cannot be empty")
+		}
+		if arn, ok := knownString(lambdaArn); ok && (!strings.HasPrefix(arn, "arn:") || !strings.Contains(arn, ":function:")) {
+			return fmt.Errorf("%q is not a lambda function arn", arn)
+		}
+	}
+
+	return nil
+}
+
+func targetPort(target *Target, port int) int {
+	if target.Port != 0 {
+		return target.Port
+	}
+
+	return port
+}
+
+// knownString returns the value of an input that is a plain string rather
+// than an output.
+func knownString(input pulumi.StringInput) (string, bool) {
+	value, ok := input.(pulumi.String)
+	return string(value), ok
+}
+
+// targetLabel names a target in errors.
+func targetLabel(target *Target) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	if id, ok := knownString(target.Id); ok {
+		return id
+	}
+
+	return "<output>"
+}
+
+// targetName is the logical name of an instance or IP attachment. The port is
+// part of it so one target can be registered on several ports, and IPs are
+// written in their canonical form without colons.
+func targetName(name string, target *Target, index int, port int) string {
+	id := target.Name
+	if id == "" {
+		known, ok := knownString(target.Id)
+		if !ok {
+			return fmt.Sprintf("%s-%d-%d", name, index+1, port)
+		}
+		id = known
+		if ip := net.ParseIP(id); ip != nil {
+			id = strings.ReplaceAll(ip.String(), ":", "-")
+		}
+	}
+
+	return fmt.Sprintf("%s-%s-%d", name, id, port)
+}
+
+// lambdaFunctionName returns the function name, and alias if any, of a
+// function ARN, which keeps logical names free of the ARN's colons.
+func lambdaFunctionName(arn string) string {
+	parts := strings.Split(arn, ":function:")
+	return strings.ReplaceAll(parts[len(parts)-1], ":", "-")
+}
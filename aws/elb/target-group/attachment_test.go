@@ -0,0 +1,192 @@
+package targetgroup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+func TestTargetName(t *testing.T) {
+	tests := []struct {
+		name   string
+		target *Target
+		index  int
+		want   string
+	}{
+		{
+			name:   "instance",
+			target: &Target{Id: pulumi.String("i-0123456789abcdef0")},
+			want:   "web-i-0123456789abcdef0-80",
+		},
+		{
+			name:   "ipv6 is canonical without colons",
+			target: &Target{Id: pulumi.String("2001:0db8:0000:0000:0000:0000:0000:0001")},
+			want:   "web-2001-db8--1-80",
+		},
+		{
+			name:   "explicit name",
+			target: &Target{Id: pulumi.String("10.0.1.10"), Name: "db"},
+			want:   "web-db-80",
+		},
+		{
+			name:   "output id is numbered",
+			target: &Target{Id: pulumi.String("10.0.1.10").ToStringOutput()},
+			index:  1,
+			want:   "web-2-80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetName("web", tt.target, tt.index, 80); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLambdaFunctionName(t *testing.T) {
+	tests := []struct {
+		arn  string
+		want string
+	}{
+		{arn: "arn:aws:lambda:eu-west-1:123456789012:function:api", want: "api"},
+		{arn: "arn:aws:lambda:eu-west-1:123456789012:function:api:live", want: "api-live"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			if got := lambdaFunctionName(tt.arn); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAttachment(t *testing.T) {
+	network := &vpc.VpcArgs{Name: "main", Cidr: "10.0.0.0/16"}
+	targetGroupArn := pulumi.String("arn:aws:elasticloadbalancing:eu-west-1:123456789012:targetgroup/web/1")
+
+	tests := []struct {
+		name    string
+		args    *AttachmentArgs
+		wantErr string
+	}{
+		{
+			name: "valid",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Instances:      []*Target{{Id: pulumi.String("i-0123456789abcdef0")}},
+				Ips:            []*Target{{Id: pulumi.String("10.0.1.10")}},
+				Vpc:            network,
+				LambdaArns:     []pulumi.StringInput{pulumi.String("arn:aws:lambda:eu-west-1:123456789012:function:api")},
+			},
+		},
+		{
+			name:    "missing target group",
+			args:    &AttachmentArgs{Name: "web"},
+			wantErr: "target group arn cannot be empty",
+		},
+		{
+			name: "not an instance id",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Instances:      []*Target{{Id: pulumi.String("web-1")}},
+			},
+			wantErr: "is not an instance id",
+		},
+		{
+			name: "port out of range",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Instances:      []*Target{{Id: pulumi.String("i-0123456789abcdef0")}},
+			},
+			wantErr: "port must be between 1 and 65535",
+		},
+		{
+			name: "ip outside the vpc",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Ips:            []*Target{{Id: pulumi.String("192.168.1.10")}},
+				Vpc:            network,
+			},
+			wantErr: "outside the vpc cidr",
+		},
+		{
+			name: "ip outside the vpc allowed",
+			args: &AttachmentArgs{
+				Name:               "web",
+				TargetGroupArn:     targetGroupArn,
+				Port:               80,
+				Ips:                []*Target{{Id: pulumi.String("192.168.1.10")}},
+				Vpc:                network,
+				AllowIpsOutsideVpc: true,
+			},
+		},
+		{
+			name: "output ip is not checked",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Ips:            []*Target{{Id: pulumi.String("192.168.1.10").ToStringOutput()}},
+				Vpc:            network,
+			},
+		},
+		{
+			name: "not an ip",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Ips:            []*Target{{Id: pulumi.String("db.internal")}},
+				Vpc:            network,
+			},
+			wantErr: "is not an ip address",
+		},
+		{
+			name: "ips without a vpc",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				Port:           80,
+				Ips:            []*Target{{Id: pulumi.String("10.0.1.10")}},
+			},
+			wantErr: "need the vpc",
+		},
+		{
+			name: "not a lambda arn",
+			args: &AttachmentArgs{
+				Name:           "web",
+				TargetGroupArn: targetGroupArn,
+				LambdaArns:     []pulumi.StringInput{pulumi.String("api")},
+			},
+			wantErr: "is not a lambda function arn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAttachment(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,19 +1,65 @@
 package targetgroup
 
 import (
+	"fmt"
+
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+// AWS checks every 30 seconds when no interval is set, and every 35 seconds
+// for lambda targets.
+const (
+	_defaultHealthCheckInterval       = 30
+	_defaultLambdaHealthCheckInterval = 35
+)
+
+var _applicationProtocols = []string{"HTTP", "HTTPS"}
+
+var _networkProtocols = []string{"TCP", "TLS", "UDP", "TCP_UDP"}
+
+var _loadBalancingAlgorithms = []string{"round_robin", "least_outstanding_requests", "weighted_random"}
+
 type TargetGroupArgs struct {
-	Name                string
-	Port                int
-	Protocol            string
-	TargetType          string
-	VpcId               string
-	ProtocolVersion     string
+	Name       string
+	Port       int
+	Protocol   string
+	TargetType string
+	// VpcId takes an output so the target group can live in a VPC created in
+	// the same program.
+	VpcId           pulumi.StringInput
+	ProtocolVersion string
+	Tags            map[string]string
+
 	HealthCheckPath     string
 	HealthCheckProtocol string
+	// HealthCheckPort defaults to the traffic port.
+	HealthCheckPort string
+	// HealthCheckInterval and HealthCheckTimeout are in seconds.
+	HealthCheckInterval int
+	HealthCheckTimeout  int
+	HealthyThreshold    int
+	UnhealthyThreshold  int
+	// HealthCheckMatcher is a list or range of HTTP codes such as "200,302"
+	// or "200-299", or gRPC codes when ProtocolVersion is GRPC.
+	HealthCheckMatcher string
+
+	// DeregistrationDelay is in seconds; zero keeps the AWS default of 300.
+	DeregistrationDelay int
+	// SlowStart is the warm-up period in seconds (30-900), zero disables it.
+	SlowStart              int
+	LoadBalancingAlgorithm string
+	Stickiness             *Stickiness
+	ProxyProtocolV2        bool
+}
+
+type Stickiness struct {
+	Enabled bool
+	// Type is lb_cookie or app_cookie for application load balancers and
+	// source_ip for network load balancers.
+	Type           string
+	CookieName     string
+	CookieDuration int
 }
 
 type TargetGroupOutput struct {
@@ -22,18 +68,61 @@ type TargetGroupOutput struct {
 }
 
 func CreateTargetGroup(ctx *pulumi.Context, args *TargetGroupArgs) (*TargetGroupOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("target group %s: %w", args.Name, err)
+	}
+
+	var port pulumi.IntPtrInput
+	if args.Port > 0 {
+		port = pulumi.IntPtr(args.Port)
+	}
+
+	var stickiness lb.TargetGroupStickinessPtrInput
+	if args.Stickiness != nil {
+		stickiness = &lb.TargetGroupStickinessArgs{
+			Enabled:        pulumi.BoolPtr(args.Stickiness.Enabled),
+			Type:           pulumi.String(args.Stickiness.Type),
+			CookieName:     stringPtr(args.Stickiness.CookieName),
+			CookieDuration: intPtr(args.Stickiness.CookieDuration),
+		}
+	}
+
+	var deregistrationDelay pulumi.IntPtrInput
+	if args.DeregistrationDelay > 0 {
+		deregistrationDelay = pulumi.IntPtr(args.DeregistrationDelay)
+	}
+
 	tg, err := lb.NewTargetGroup(ctx, args.Name, &lb.TargetGroupArgs{
 		Name:            pulumi.String(args.Name),
-		Port:            pulumi.IntPtr(args.Port),
-		Protocol:        pulumi.String(args.Protocol),
+		Port:            port,
+		Protocol:        stringPtr(args.Protocol),
 		TargetType:      pulumi.String(args.TargetType),
-		VpcId:           pulumi.String(args.VpcId),
-		ProtocolVersion: pulumi.String(args.ProtocolVersion),
+		VpcId:           args.VpcId,
+		ProtocolVersion: stringPtr(args.ProtocolVersion),
 		HealthCheck: &lb.TargetGroupHealthCheckArgs{
-			Path:     pulumi.String(args.HealthCheckPath),
-			Protocol: pulumi.String(args.HealthCheckProtocol),
+			Path:               stringPtr(args.HealthCheckPath),
+			Protocol:           stringPtr(args.HealthCheckProtocol),
+			Port:               stringPtr(args.HealthCheckPort),
+			Interval:           intPtr(args.HealthCheckInterval),
+			Timeout:            intPtr(args.HealthCheckTimeout),
+			HealthyThreshold:   intPtr(args.HealthyThreshold),
+			UnhealthyThreshold: intPtr(args.UnhealthyThreshold),
+			Matcher:            stringPtr(args.HealthCheckMatcher),
 		},
-		ProxyProtocolV2: pulumi.Bool(false),
+		DeregistrationDelay:        deregistrationDelay,
+		SlowStart:                  intPtr(args.SlowStart),
+		LoadBalancingAlgorithmType: stringPtr(args.LoadBalancingAlgorithm),
+		Stickiness:                 stickiness,
+		ProxyProtocolV2:            pulumi.Bool(args.ProxyProtocolV2),
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name": args.Name,
+			}, args.Tags),
+		),
 	})
 	if err != nil {
 		return nil, err
@@ -44,3 +133,163 @@ func CreateTargetGroup(ctx *pulumi.Context, args *TargetGroupArgs) (*TargetGroup
 		TargetGroupId:  tg.ID(),
 	}, nil
 }
+
+// validate rejects combinations that the ELB API only reports at apply time.
+func validate(args *TargetGroupArgs) error {
+	if args.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+
+	if args.TargetType == "lambda" {
+		if args.Port != 0 || args.Protocol != "" || args.ProtocolVersion != "" || args.VpcId != nil {
+			return fmt.Errorf("lambda targets do not take a port, protocol, protocol version or vpc")
+		}
+		if args.Stickiness != nil {
+			return fmt.Errorf("lambda targets do not support stickiness")
+		}
+	} else {
+		if args.Port < 1 || args.Port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535, got %d", args.Port)
+		}
+		if args.VpcId == nil {
+			return fmt.Errorf("vpc id cannot be empty for %s targets", args.TargetType)
+		}
+		if !contains(_applicationProtocols, args.Protocol) && !contains(_networkProtocols, args.Protocol) {
+			return fmt.Errorf("unsupported protocol %q", args.Protocol)
+		}
+	}
+
+	application := contains(_applicationProtocols, args.Protocol)
+
+	if args.ProtocolVersion != "" {
+		if !application {
+			return fmt.Errorf("protocol version %s requires HTTP or HTTPS, got %q", args.ProtocolVersion, args.Protocol)
+		}
+		if !contains([]string{"HTTP1", "HTTP2", "GRPC"}, args.ProtocolVersion) {
+			return fmt.Errorf("unsupported protocol version %q", args.ProtocolVersion)
+		}
+	}
+
+	if args.HealthCheckInterval != 0 && (args.HealthCheckInterval < 5 || args.HealthCheckInterval > 300) {
+		return fmt.Errorf("health check interval must be between 5 and 300 seconds, got %d", args.HealthCheckInterval)
+	}
+	if args.HealthCheckTimeout != 0 && (args.HealthCheckTimeout < 2 || args.HealthCheckTimeout > 120) {
+		return fmt.Errorf("health check timeout must be between 2 and 120 seconds, got %d", args.HealthCheckTimeout)
+	}
+	interval := healthCheckInterval(args)
+	if args.HealthCheckTimeout >= interval {
+		return fmt.Errorf("health check timeout %d must be shorter than the interval %d", args.HealthCheckTimeout, interval)
+	}
+	for _, threshold := range []int{args.HealthyThreshold, args.UnhealthyThreshold} {
+		if threshold != 0 && (threshold < 2 || threshold > 10) {
+			return fmt.Errorf("health check thresholds must be between 2 and 10, got %d", threshold)
+		}
+	}
+	if args.HealthCheckProtocol == "TCP" && (args.HealthCheckPath != "" || args.HealthCheckMatcher != "") {
+		return fmt.Errorf("tcp health checks do not take a path or matcher")
+	}
+
+	if args.DeregistrationDelay < 0 || args.DeregistrationDelay > 3600 {
+		return fmt.Errorf("deregistration delay must be between 0 and 3600 seconds, got %d", args.DeregistrationDelay)
+	}
+
+	if args.SlowStart != 0 {
+		if !application {
+			return fmt.Errorf("slow start requires HTTP or HTTPS, got %q", args.Protocol)
+		}
+		if args.SlowStart < 30 || args.SlowStart > 900 {
+			return fmt.Errorf("slow start must be between 30 and 900 seconds, got %d", args.SlowStart)
+		}
+	}
+
+	if args.LoadBalancingAlgorithm != "" {
+		if !application {
+			return fmt.Errorf("load balancing algorithm requires HTTP or HTTPS, got %q", args.Protocol)
+		}
+		if !contains(_loadBalancingAlgorithms, args.LoadBalancingAlgorithm) {
+			return fmt.Errorf("unsupported load balancing algorithm %q", args.LoadBalancingAlgorithm)
+		}
+	}
+
+	if args.Stickiness != nil {
+		switch args.Stickiness.Type {
+		case "lb_cookie":
+			if !application {
+				return fmt.Errorf("lb_cookie stickiness requires HTTP or HTTPS, got %q", args.Protocol)
+			}
+		case "app_cookie":
+			if !application {
+				return fmt.Errorf("app_cookie stickiness requires HTTP or HTTPS, got %q", args.Protocol)
+			}
+			if args.Stickiness.CookieName == "" {
+				return fmt.Errorf("app_cookie stickiness requires a cookie name")
+			}
+		case "source_ip":
+			if application {
+				return fmt.Errorf("source_ip stickiness is not supported for %s", args.Protocol)
+			}
+		default:
+			return fmt.Errorf("unsupported stickiness type %q", args.Stickiness.Type)
+		}
+		if args.Stickiness.CookieDuration != 0 && (args.Stickiness.CookieDuration < 1 || args.Stickiness.CookieDuration > 604800) {
+			return fmt.Errorf("cookie duration must be between 1 and 604800 seconds, got %d", args.Stickiness.CookieDuration)
+		}
+	}
+
+	if args.ProxyProtocolV2 && !contains(_networkProtocols, args.Protocol) {
+		return fmt.Errorf("proxy protocol v2 requires a network protocol, got %q", args.Protocol)
+	}
+
+	return nil
+}
+
+// healthCheckInterval is the interval AWS uses, which depends on the target
+// type when none is set.
+func healthCheckInterval(args *TargetGroupArgs) int {
+	switch {
+	case args.HealthCheckInterval != 0:
+		return args.HealthCheckInterval
+	case args.TargetType == "lambda":
+		return _defaultLambdaHealthCheckInterval
+	default:
+		return _defaultHealthCheckInterval
+	}
+}
+
+func stringPtr(value string) pulumi.StringPtrInput {
+	if value == "" {
+		return nil
+	}
+
+	return pulumi.StringPtr(value)
+}
+
+func intPtr(value int) pulumi.IntPtrInput {
+	if value == 0 {
+		return nil
+	}
+
+	return pulumi.IntPtr(value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
@@ -0,0 +1,88 @@
+package targetgroup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func TestHealthCheckInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		args *TargetGroupArgs
+		want int
+	}{
+		{name: "instance default", args: &TargetGroupArgs{TargetType: "instance"}, want: 30},
+		{name: "ip default", args: &TargetGroupArgs{TargetType: "ip"}, want: 30},
+		{name: "lambda default", args: &TargetGroupArgs{TargetType: "lambda"}, want: 35},
+		{name: "explicit", args: &TargetGroupArgs{TargetType: "lambda", HealthCheckInterval: 60}, want: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthCheckInterval(tt.args); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	vpcId := pulumi.String("vpc-0123456789abcdef0")
+
+	tests := []struct {
+		name    string
+		args    *TargetGroupArgs
+		wantErr string
+	}{
+		{
+			name: "http",
+			args: &TargetGroupArgs{Name: "web", TargetType: "ip", Port: 80, Protocol: "HTTP", VpcId: vpcId},
+		},
+		{
+			name: "lambda timeout within its default interval",
+			args: &TargetGroupArgs{Name: "api", TargetType: "lambda", HealthCheckTimeout: 32},
+		},
+		{
+			name:    "instance timeout beyond its default interval",
+			args:    &TargetGroupArgs{Name: "web", TargetType: "instance", Port: 80, Protocol: "HTTP", VpcId: vpcId, HealthCheckTimeout: 32},
+			wantErr: "must be shorter than the interval 30",
+		},
+		{
+			name:    "lambda with a port",
+			args:    &TargetGroupArgs{Name: "api", TargetType: "lambda", Port: 80},
+			wantErr: "lambda targets do not take a port",
+		},
+		{
+			name:    "missing vpc",
+			args:    &TargetGroupArgs{Name: "web", TargetType: "ip", Port: 80, Protocol: "HTTP"},
+			wantErr: "vpc id cannot be empty",
+		},
+		{
+			name:    "source ip stickiness on http",
+			args:    &TargetGroupArgs{Name: "web", TargetType: "ip", Port: 80, Protocol: "HTTP", VpcId: vpcId, Stickiness: &Stickiness{Type: "source_ip"}},
+			wantErr: "source_ip stickiness is not supported",
+		},
+		{
+			name:    "proxy protocol on http",
+			args:    &TargetGroupArgs{Name: "web", TargetType: "ip", Port: 80, Protocol: "HTTP", VpcId: vpcId, ProxyProtocolV2: true},
+			wantErr: "proxy protocol v2 requires a network protocol",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package rds
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/secretsmanager"
+	"github.com/pulumi/pulumi-random/sdk/v4/go/random"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	// PasswordPulumiSecret keeps the master password only in the encrypted
+	// stack state and exports it as `<name>-password`.
+	PasswordPulumiSecret = "pulumi_secret"
+	// PasswordSecretsManager also stores the password with the connection
+	// details in a Secrets Manager secret named `rds/<name>/master`.
+	PasswordSecretsManager = "secrets_manager"
+)
+
+var _passwordStorages = []string{PasswordPulumiSecret, PasswordSecretsManager}
+
+// createPassword generates the master password once; it is kept in the state
+// and is stable across updates.
+func createPassword(ctx *pulumi.Context, name string) (pulumi.StringOutput, error) {
+	password, err := random.NewRandomPassword(ctx, fmt.Sprintf("%s-rds-password", name), &random.RandomPasswordArgs{
+		Length:  pulumi.Int(32),
+		Special: pulumi.Bool(true),
+		// RDS rejects /, @, " and spaces
+		OverrideSpecial: pulumi.String("!#$%&*()-_=+[]{}<>:?"),
+	})
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	return pulumi.ToSecret(password.Result).(pulumi.StringOutput), nil
+}
+
+func createPasswordSecret(ctx *pulumi.Context, args *DatabaseArgs, instance *rds.Instance, password pulumi.StringOutput, kmsKeyArn pulumi.StringOutput, tags map[string]string) (pulumi.StringOutput, error) {
+	secret, err := secretsmanager.NewSecret(ctx, fmt.Sprintf("%s-rds-secret", args.Name), &secretsmanager.SecretArgs{
+		Name:        pulumi.String(fmt.Sprintf("rds/%s/master", args.Name)),
+		Description: pulumi.String(fmt.Sprintf("Master credentials of the %s database", args.Name)),
+		KmsKeyId:    kmsKeyArn,
+		Tags:        pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// the same keys RDS uses for the secrets it manages
+	secretString := pulumi.All(password, instance.Address, instance.Port).ApplyT(func(values []interface{}) (string, error) {
+		document, err := json.Marshal(map[string]interface{}{
+			"engine":   "postgres",
+			"username": args.Username,
+			"password": values[0].(string),
+			"host":     values[1].(string),
+			"port":     values[2].(int),
+			"dbname":   args.DatabaseName,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return string(document), nil
+	}).(pulumi.StringOutput)
+
+	_, err = secretsmanager.NewSecretVersion(ctx, fmt.Sprintf("%s-rds-secret", args.Name), &secretsmanager.SecretVersionArgs{
+		SecretId:     secret.ID(),
+		SecretString: pulumi.ToSecret(secretString).(pulumi.StringOutput),
+	})
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	return secret.Arn, nil
+}
@@ -0,0 +1,442 @@
+package rds
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/kms"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/rds"
+	"github.com/pulumi/pulumi-random/sdk/v4/go/random"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+const (
+	_defaultEngineVersion     = "16"
+	_defaultInstanceClass     = "db.t4g.medium"
+	_defaultAllocatedStorage  = 20
+	_defaultUsername          = "postgres"
+	_defaultBackupWindow      = "03:00-04:00"
+	_defaultMaintenanceWindow = "sun:04:30-sun:05:30"
+	_defaultBackupRetention   = 7
+	_postgresPort             = 5432
+	_minutesPerDay            = 24 * 60
+)
+
+// _defaultParameters are set on every parameter group unless overridden.
+var _defaultParameters = map[string]string{
+	"rds.force_ssl":              "1",
+	"log_min_duration_statement": "1000",
+}
+
+var (
+	_identifier    = regexp.MustCompile(`^[a-z][a-z0-9]*(?:-[a-z0-9]+)*$`)
+	_databaseName  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+	_engineVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	_window        = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]-([01][0-9]|2[0-3]):[0-5][0-9]$`)
+	_weeklyWindow  = regexp.MustCompile(`^(mon|tue|wed|thu|fri|sat|sun):([01][0-9]|2[0-3]):[0-5][0-9]-(mon|tue|wed|thu|fri|sat|sun):([01][0-9]|2[0-3]):[0-5][0-9]$`)
+)
+
+var _weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+type DatabaseArgs struct {
+	Name        string
+	Environment string
+	Tags        map[string]string
+
+	// Vpc is the output of vpc.CreateVpc. The database is placed in its
+	// private subnets.
+	Vpc *vpc.VpcOutput
+	// AppSecurityGroupId is the only security group admitted to the
+	// database.
+	AppSecurityGroupId pulumi.StringInput
+
+	// EngineVersion is a major or full PostgreSQL version, defaulting to 16.
+	EngineVersion    string
+	InstanceClass    string
+	AllocatedStorage int
+	// MaxAllocatedStorage enables storage autoscaling up to this many GiB.
+	MaxAllocatedStorage int
+	DatabaseName        string
+	Username            string
+	// Parameters are merged over the defaults, which force SSL and log
+	// statements slower than a second.
+	Parameters map[string]string
+
+	// KmsKeyArn encrypts the storage, Performance Insights and the password
+	// secret. When empty a key is created.
+	KmsKeyArn string
+	MultiAz   bool
+
+	// BackupWindow and MaintenanceWindow are in UTC and must not overlap.
+	BackupWindow        string
+	BackupRetentionDays int
+	MaintenanceWindow   string
+
+	// PerformanceInsights defaults to true, keeping 7 days of data.
+	PerformanceInsights *bool
+	// DeletionProtection defaults to true. A final snapshot is always taken,
+	// named <Name>-final-<random suffix> so recreating the database does not
+	// collide with the snapshot of the previous one.
+	DeletionProtection *bool
+
+	// PasswordStorage is pulumi_secret or secrets_manager, defaulting to
+	// pulumi_secret.
+	PasswordStorage string
+}
+
+type DatabaseOutput struct {
+	Identifier      pulumi.StringOutput
+	Arn             pulumi.StringOutput
+	Address         pulumi.StringOutput
+	Port            pulumi.IntOutput
+	SecurityGroupId pulumi.IDOutput
+	KmsKeyArn       pulumi.StringOutput
+	Username        string
+	// Password is a secret.
+	Password pulumi.StringOutput
+	// SecretArn is only set with the secrets_manager password storage.
+	SecretArn pulumi.StringOutput
+}
+
+func CreateDatabase(ctx *pulumi.Context, args *DatabaseArgs) (*DatabaseOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	setDefaults(args)
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("rds database %s: %w", args.Name, err)
+	}
+
+	tags := merge(map[string]string{
+		"Name":        args.Name,
+		"Environment": args.Environment,
+	}, args.Tags)
+
+	var kmsKeyArn pulumi.StringOutput
+	if args.KmsKeyArn != "" {
+		kmsKeyArn = pulumi.String(args.KmsKeyArn).ToStringOutput()
+	} else {
+		key, err := kms.NewKey(ctx, fmt.Sprintf("%s-rds", args.Name), &kms.KeyArgs{
+			Description:       pulumi.String(fmt.Sprintf("RDS encryption for %s", args.Name)),
+			EnableKeyRotation: pulumi.Bool(true),
+			Tags:              pulumi.ToStringMap(tags),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = kms.NewAlias(ctx, fmt.Sprintf("%s-rds", args.Name), &kms.AliasArgs{
+			Name:        pulumi.String(fmt.Sprintf("alias/rds/%s", args.Name)),
+			TargetKeyId: key.KeyId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		kmsKeyArn = key.Arn
+	}
+
+	subnetGroup, err := rds.NewSubnetGroup(ctx, fmt.Sprintf("%s-rds-subnets", args.Name), &rds.SubnetGroupArgs{
+		Name:      pulumi.String(args.Name),
+		SubnetIds: args.Vpc.PrivateSubnetIds,
+		Tags:      pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	securityGroup, err := ec2.NewSecurityGroup(ctx, fmt.Sprintf("%s-rds-sg", args.Name), &ec2.SecurityGroupArgs{
+		Name:  pulumi.String(fmt.Sprintf("%s-rds-sg", args.Name)),
+		VpcId: args.Vpc.VpcId,
+		Ingress: ec2.SecurityGroupIngressArray{
+			ec2.SecurityGroupIngressArgs{
+				Description:    pulumi.String("app"),
+				FromPort:       pulumi.Int(_postgresPort),
+				ToPort:         pulumi.Int(_postgresPort),
+				Protocol:       pulumi.String("tcp"),
+				SecurityGroups: pulumi.StringArray{args.AppSecurityGroupId},
+			},
+		},
+		Tags: pulumi.ToStringMap(
+			merge(tags, map[string]string{
+				"Name": fmt.Sprintf("%s-rds-sg", args.Name),
+			}),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := merge(_defaultParameters, args.Parameters)
+	names := []string{}
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parameterGroupParameters := rds.ParameterGroupParameterArray{}
+	for _, name := range names {
+		parameterGroupParameters = append(parameterGroupParameters, rds.ParameterGroupParameterArgs{
+			Name:  pulumi.String(name),
+			Value: pulumi.String(parameters[name]),
+			// static parameters reject immediate and dynamic ones accept both
+			ApplyMethod: pulumi.String("pending-reboot"),
+		})
+	}
+
+	parameterGroup, err := rds.NewParameterGroup(ctx, fmt.Sprintf("%s-rds-params", args.Name), &rds.ParameterGroupArgs{
+		Name:       pulumi.String(args.Name),
+		Family:     pulumi.String(parameterGroupFamily(args.EngineVersion)),
+		Parameters: parameterGroupParameters,
+		Tags:       pulumi.ToStringMap(tags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := createPassword(ctx, args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotSuffix, err := random.NewRandomId(ctx, fmt.Sprintf("%s-rds-final-snapshot", args.Name), &random.RandomIdArgs{
+		ByteLength: pulumi.Int(4),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instanceArgs := &rds.InstanceArgs{
+		Identifier:                   pulumi.String(args.Name),
+		Engine:                       pulumi.String("postgres"),
+		EngineVersion:                pulumi.String(args.EngineVersion),
+		InstanceClass:                pulumi.String(args.InstanceClass),
+		AllocatedStorage:             pulumi.Int(args.AllocatedStorage),
+		StorageType:                  pulumi.String("gp3"),
+		StorageEncrypted:             pulumi.Bool(true),
+		KmsKeyId:                     kmsKeyArn,
+		DbName:                       pulumi.String(args.DatabaseName),
+		Username:                     pulumi.String(args.Username),
+		Password:                     password,
+		Port:                         pulumi.Int(_postgresPort),
+		DbSubnetGroupName:            subnetGroup.Name,
+		VpcSecurityGroupIds:          pulumi.StringArray{securityGroup.ID().ToStringOutput()},
+		ParameterGroupName:           parameterGroup.Name,
+		PubliclyAccessible:           pulumi.Bool(false),
+		MultiAz:                      pulumi.Bool(args.MultiAz),
+		BackupWindow:                 pulumi.String(args.BackupWindow),
+		BackupRetentionPeriod:        pulumi.Int(args.BackupRetentionDays),
+		MaintenanceWindow:            pulumi.String(args.MaintenanceWindow),
+		CopyTagsToSnapshot:           pulumi.Bool(true),
+		DeletionProtection:           pulumi.Bool(*args.DeletionProtection),
+		FinalSnapshotIdentifier:      pulumi.Sprintf("%s-final-%s", args.Name, snapshotSuffix.Hex),
+		EnabledCloudwatchLogsExports: pulumi.ToStringArray([]string{"postgresql", "upgrade"}),
+		PerformanceInsightsEnabled:   pulumi.Bool(*args.PerformanceInsights),
+		Tags:                         pulumi.ToStringMap(tags),
+	}
+	if args.MaxAllocatedStorage > 0 {
+		instanceArgs.MaxAllocatedStorage = pulumi.Int(args.MaxAllocatedStorage)
+	}
+	if *args.PerformanceInsights {
+		instanceArgs.PerformanceInsightsKmsKeyId = kmsKeyArn
+		instanceArgs.PerformanceInsightsRetentionPeriod = pulumi.Int(7)
+	}
+
+	instance, err := rds.NewInstance(ctx, fmt.Sprintf("%s-rds", args.Name), instanceArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &DatabaseOutput{
+		Identifier:      instance.Identifier,
+		Arn:             instance.Arn,
+		Address:         instance.Address,
+		Port:            instance.Port,
+		SecurityGroupId: securityGroup.ID(),
+		KmsKeyArn:       kmsKeyArn,
+		Username:        args.Username,
+		Password:        password,
+	}
+
+	if args.PasswordStorage == PasswordSecretsManager {
+		output.SecretArn, err = createPasswordSecret(ctx, args, instance, password, kmsKeyArn, tags)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ctx.Export(fmt.Sprintf("%s-password", args.Name), password)
+	}
+	ctx.Export(fmt.Sprintf("%s-address", args.Name), instance.Address)
+
+	return output, nil
+}
+
+// parameterGroupFamily maps 16 or 16.4 to postgres16.
+func parameterGroupFamily(engineVersion string) string {
+	return fmt.Sprintf("postgres%s", strings.Split(engineVersion, ".")[0])
+}
+
+func setDefaults(args *DatabaseArgs) {
+	if args.EngineVersion == "" {
+		args.EngineVersion = _defaultEngineVersion
+	}
+	if args.InstanceClass == "" {
+		args.InstanceClass = _defaultInstanceClass
+	}
+	if args.AllocatedStorage == 0 {
+		args.AllocatedStorage = _defaultAllocatedStorage
+	}
+	if args.DatabaseName == "" {
+		args.DatabaseName = strings.ReplaceAll(args.Name, "-", "_")
+	}
+	if args.Username == "" {
+		args.Username = _defaultUsername
+	}
+	if args.BackupWindow == "" {
+		args.BackupWindow = _defaultBackupWindow
+	}
+	if args.BackupRetentionDays == 0 {
+		args.BackupRetentionDays = _defaultBackupRetention
+	}
+	if args.MaintenanceWindow == "" {
+		args.MaintenanceWindow = _defaultMaintenanceWindow
+	}
+	if args.PerformanceInsights == nil {
+		performanceInsights := true
+		args.PerformanceInsights = &performanceInsights
+	}
+	if args.DeletionProtection == nil {
+		deletionProtection := true
+		args.DeletionProtection = &deletionProtection
+	}
+	if args.PasswordStorage == "" {
+		args.PasswordStorage = PasswordPulumiSecret
+	}
+}
+
+func validate(args *DatabaseArgs) error {
+	if !_identifier.MatchString(args.Name) || len(args.Name) > 63 {
+		return fmt.Errorf("name must start with a letter and hold only lowercase letters, digits and single hyphens")
+	}
+	if args.Vpc == nil {
+		return fmt.Errorf("vpc cannot be nil")
+	}
+	if args.AppSecurityGroupId == nil {
+		return fmt.Errorf("app security group id cannot be nil")
+	}
+	if !_engineVersion.MatchString(args.EngineVersion) {
+		return fmt.Errorf("invalid engine version %q", args.EngineVersion)
+	}
+	if args.AllocatedStorage < 20 {
+		return fmt.Errorf("allocated storage must be at least 20 GiB, got %d", args.AllocatedStorage)
+	}
+	if args.MaxAllocatedStorage != 0 && args.MaxAllocatedStorage <= args.AllocatedStorage {
+		return fmt.Errorf("max allocated storage %d must be above allocated storage %d", args.MaxAllocatedStorage, args.AllocatedStorage)
+	}
+	if args.BackupRetentionDays < 1 || args.BackupRetentionDays > 35 {
+		return fmt.Errorf("backup retention must be between 1 and 35 days, got %d", args.BackupRetentionDays)
+	}
+	if !_window.MatchString(args.BackupWindow) {
+		return fmt.Errorf("backup window must look like hh:mm-hh:mm, got %q", args.BackupWindow)
+	}
+	if !_weeklyWindow.MatchString(args.MaintenanceWindow) {
+		return fmt.Errorf("maintenance window must look like ddd:hh:mm-ddd:hh:mm, got %q", args.MaintenanceWindow)
+	}
+	backupStart, backupEnd := dailyWindow(args.BackupWindow)
+	if backupEnd-backupStart < 30 {
+		return fmt.Errorf("backup window %s must be at least 30 minutes", args.BackupWindow)
+	}
+	maintenanceStart, maintenanceEnd := weeklyWindow(args.MaintenanceWindow)
+	if maintenanceEnd-maintenanceStart < 30 {
+		return fmt.Errorf("maintenance window %s must be at least 30 minutes", args.MaintenanceWindow)
+	}
+	// the backup window repeats every day, including the day after the
+	// maintenance window when it wraps past the end of the week
+	for day := -1; day <= 14; day++ {
+		if backupStart+day*_minutesPerDay < maintenanceEnd && maintenanceStart < backupEnd+day*_minutesPerDay {
+			return fmt.Errorf("backup window %s overlaps maintenance window %s", args.BackupWindow, args.MaintenanceWindow)
+		}
+	}
+	if !contains(_passwordStorages, args.PasswordStorage) {
+		return fmt.Errorf("unsupported password storage %q", args.PasswordStorage)
+	}
+	if !_databaseName.MatchString(args.DatabaseName) || len(args.DatabaseName) > 63 {
+		return fmt.Errorf("invalid database name %q", args.DatabaseName)
+	}
+	if args.Username == "rdsadmin" {
+		return fmt.Errorf("username rdsadmin is reserved")
+	}
+
+	return nil
+}
+
+// dailyWindow returns the start and end of hh:mm-hh:mm in minutes after
+// midnight; the end is moved to the next day when the window wraps.
+func dailyWindow(window string) (int, int) {
+	bounds := strings.Split(window, "-")
+	start, end := minutes(bounds[0]), minutes(bounds[1])
+	if end <= start {
+		end += _minutesPerDay
+	}
+
+	return start, end
+}
+
+// weeklyWindow returns the start and end of ddd:hh:mm-ddd:hh:mm in minutes
+// after Monday midnight; the end is moved to the next week when the window
+// wraps.
+func weeklyWindow(window string) (int, int) {
+	bounds := strings.Split(window, "-")
+	start, end := weekMinutes(bounds[0]), weekMinutes(bounds[1])
+	if end <= start {
+		end += 7 * _minutesPerDay
+	}
+
+	return start, end
+}
+
+func weekMinutes(value string) int {
+	day := 0
+	for index, weekday := range _weekdays {
+		if weekday == value[:3] {
+			day = index
+		}
+	}
+
+	return day*_minutesPerDay + minutes(value[4:])
+}
+
+// minutes converts a validated hh:mm to minutes after midnight.
+func minutes(value string) int {
+	var hours, mins int
+	fmt.Sscanf(value, "%d:%d", &hours, &mins)
+
+	return hours*60 + mins
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
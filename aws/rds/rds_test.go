@@ -0,0 +1,178 @@
+package rds
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(args *DatabaseArgs)
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			change: func(args *DatabaseArgs) {},
+		},
+		{
+			name:    "invalid name",
+			change:  func(args *DatabaseArgs) { args.Name = "App--db" },
+			wantErr: "name must start with a letter",
+		},
+		{
+			name:    "missing vpc",
+			change:  func(args *DatabaseArgs) { args.Vpc = nil },
+			wantErr: "vpc cannot be nil",
+		},
+		{
+			name:    "missing app security group",
+			change:  func(args *DatabaseArgs) { args.AppSecurityGroupId = nil },
+			wantErr: "app security group id cannot be nil",
+		},
+		{
+			name:    "invalid engine version",
+			change:  func(args *DatabaseArgs) { args.EngineVersion = "16.x" },
+			wantErr: "invalid engine version",
+		},
+		{
+			name:    "too little storage",
+			change:  func(args *DatabaseArgs) { args.AllocatedStorage = 10 },
+			wantErr: "at least 20 GiB",
+		},
+		{
+			name:    "max storage below allocated",
+			change:  func(args *DatabaseArgs) { args.AllocatedStorage = 100; args.MaxAllocatedStorage = 50 },
+			wantErr: "must be above allocated storage",
+		},
+		{
+			name:    "retention too long",
+			change:  func(args *DatabaseArgs) { args.BackupRetentionDays = 36 },
+			wantErr: "backup retention must be between 1 and 35 days",
+		},
+		{
+			name:    "malformed backup window",
+			change:  func(args *DatabaseArgs) { args.BackupWindow = "3:00-4:00" },
+			wantErr: "backup window must look like hh:mm-hh:mm",
+		},
+		{
+			name:    "malformed maintenance window",
+			change:  func(args *DatabaseArgs) { args.MaintenanceWindow = "sunday:04:30-sunday:05:30" },
+			wantErr: "maintenance window must look like ddd:hh:mm-ddd:hh:mm",
+		},
+		{
+			name:    "short backup window",
+			change:  func(args *DatabaseArgs) { args.BackupWindow = "03:00-03:20" },
+			wantErr: "must be at least 30 minutes",
+		},
+		{
+			name:    "short maintenance window",
+			change:  func(args *DatabaseArgs) { args.MaintenanceWindow = "sun:04:30-sun:04:45" },
+			wantErr: "must be at least 30 minutes",
+		},
+		{
+			name:   "adjacent windows",
+			change: func(args *DatabaseArgs) { args.MaintenanceWindow = "tue:04:00-tue:05:00" },
+		},
+		{
+			name:    "overlapping windows",
+			change:  func(args *DatabaseArgs) { args.MaintenanceWindow = "wed:03:30-wed:04:30" },
+			wantErr: "overlaps maintenance window",
+		},
+		{
+			name: "backup window wrapping midnight",
+			change: func(args *DatabaseArgs) {
+				args.BackupWindow = "23:30-00:30"
+				args.MaintenanceWindow = "mon:00:00-mon:01:00"
+			},
+			wantErr: "overlaps maintenance window",
+		},
+		{
+			name: "maintenance window wrapping the week",
+			change: func(args *DatabaseArgs) {
+				args.BackupWindow = "00:00-00:30"
+				args.MaintenanceWindow = "sun:23:45-mon:00:30"
+			},
+			wantErr: "overlaps maintenance window",
+		},
+		{
+			name: "maintenance window spanning days",
+			change: func(args *DatabaseArgs) {
+				args.BackupWindow = "12:00-13:00"
+				args.MaintenanceWindow = "mon:22:00-tue:14:00"
+			},
+			wantErr: "overlaps maintenance window",
+		},
+		{
+			name:    "unknown password storage",
+			change:  func(args *DatabaseArgs) { args.PasswordStorage = "vault" },
+			wantErr: "unsupported password storage",
+		},
+		{
+			name:    "invalid database name",
+			change:  func(args *DatabaseArgs) { args.DatabaseName = "1app" },
+			wantErr: "invalid database name",
+		},
+		{
+			name:    "reserved username",
+			change:  func(args *DatabaseArgs) { args.Username = "rdsadmin" },
+			wantErr: "username rdsadmin is reserved",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &DatabaseArgs{
+				Name:               "app-db",
+				Vpc:                &vpc.VpcOutput{},
+				AppSecurityGroupId: pulumi.String("sg-0123456789abcdef0"),
+			}
+			setDefaults(args)
+			tt.change(args)
+
+			err := validate(args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWeeklyWindow(t *testing.T) {
+	tests := []struct {
+		window    string
+		wantStart int
+		wantEnd   int
+	}{
+		{window: "mon:00:00-mon:01:00", wantStart: 0, wantEnd: 60},
+		{window: "sun:04:30-sun:05:30", wantStart: 6*_minutesPerDay + 270, wantEnd: 6*_minutesPerDay + 330},
+		{window: "sun:23:30-mon:00:30", wantStart: 6*_minutesPerDay + 1410, wantEnd: 7*_minutesPerDay + 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			start, end := weeklyWindow(tt.window)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("got %d-%d, want %d-%d", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParameterGroupFamily(t *testing.T) {
+	for version, want := range map[string]string{"16": "postgres16", "16.4": "postgres16", "15.7": "postgres15"} {
+		if got := parameterGroupFamily(version); got != want {
+			t.Errorf("version %s got family %s, want %s", version, got, want)
+		}
+	}
+}
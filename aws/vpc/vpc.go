@@ -16,6 +16,19 @@ const (
 
 	// single nat gateway
 	_enableNatGateway = true
+
+	// TierTag marks every subnet as private or public so other modules can
+	// look subnets up by VPC id and tier.
+	TierTag     = "Tier"
+	TierPrivate = "private"
+	TierPublic  = "public"
+
+	// tags the AWS Load Balancer Controller and Karpenter discover subnets
+	// and security groups by
+	KubernetesClusterTagPrefix   = "kubernetes.io/cluster/"
+	KubernetesElbRoleTag         = "kubernetes.io/role/elb"
+	KubernetesInternalElbRoleTag = "kubernetes.io/role/internal-elb"
+	KarpenterDiscoveryTag        = "karpenter.sh/discovery"
 )
 
 type VpcArgs struct {
@@ -24,11 +37,16 @@ type VpcArgs struct {
 	Tags              map[string]string
 	PrivateSubnetTags map[string]string
 	PublicSubnetTags  map[string]string
+	// ClusterName is the EKS cluster the VPC is built for. When set, subnets
+	// get the tags load balancers and Karpenter use to discover them.
+	ClusterName string
 }
 
 type VpcOutput struct {
-	VpcId  pulumi.IDOutput
-	VpcArn pulumi.StringOutput
+	VpcId            pulumi.IDOutput
+	VpcArn           pulumi.StringOutput
+	PrivateSubnetIds pulumi.StringArray
+	PublicSubnetIds  pulumi.StringArray
 }
 
 func CreateVpc(ctx *pulumi.Context, args *VpcArgs) (*VpcOutput, error) {
@@ -39,8 +57,8 @@ func CreateVpc(ctx *pulumi.Context, args *VpcArgs) (*VpcOutput, error) {
 	name := args.Name
 	vcpCidr := args.Cidr
 	tags := args.Tags
-	privateSubnetTags := args.PrivateSubnetTags
-	publicSubnetTags := args.PublicSubnetTags
+	privateSubnetTags := merge(KubernetesSubnetTags(args.ClusterName, TierPrivate), args.PrivateSubnetTags)
+	publicSubnetTags := merge(KubernetesSubnetTags(args.ClusterName, TierPublic), args.PublicSubnetTags)
 
 	azs, err := aws.GetAvailabilityZones(ctx, &aws.GetAvailabilityZonesArgs{
 		State: pulumi.StringRef("available"),
@@ -101,7 +119,8 @@ func CreateVpc(ctx *pulumi.Context, args *VpcArgs) (*VpcOutput, error) {
 				AvailabilityZone: pulumi.String(azs.Names[index]),
 				Tags: pulumi.ToStringMap(
 					merge(map[string]string{
-						"Name": fmt.Sprintf("%s-private-%d", name, index+1),
+						"Name":  fmt.Sprintf("%s-private-%d", name, index+1),
+						TierTag: TierPrivate,
 					}, privateSubnetTags),
 				),
 			})
@@ -123,7 +142,8 @@ func CreateVpc(ctx *pulumi.Context, args *VpcArgs) (*VpcOutput, error) {
 				AvailabilityZone: pulumi.String(azs.Names[index]),
 				Tags: pulumi.ToStringMap(
 					merge(map[string]string{
-						"Name": fmt.Sprintf("%s-public-%d", name, index+1),
+						"Name":  fmt.Sprintf("%s-public-%d", name, index+1),
+						TierTag: TierPublic,
 					}, publicSubnetTags),
 				),
 			})
@@ -248,12 +268,46 @@ func CreateVpc(ctx *pulumi.Context, args *VpcArgs) (*VpcOutput, error) {
 		}
 	}
 
+	privateSubnetIds := pulumi.StringArray{}
+	for _, subnet := range privateSubnets {
+		privateSubnetIds = append(privateSubnetIds, subnet.ID().ToStringOutput())
+	}
+
+	publicSubnetIds := pulumi.StringArray{}
+	for _, subnet := range publicSubnets {
+		publicSubnetIds = append(publicSubnetIds, subnet.ID().ToStringOutput())
+	}
+
 	return &VpcOutput{
-		VpcId:  vpc.ID(),
-		VpcArn: vpc.Arn,
+		VpcId:            vpc.ID(),
+		VpcArn:           vpc.Arn,
+		PrivateSubnetIds: privateSubnetIds,
+		PublicSubnetIds:  publicSubnetIds,
 	}, nil
 }
 
+// KubernetesSubnetTags returns the discovery tags for subnets of the given
+// tier in a VPC shared with clusterName. Internal load balancers and Karpenter
+// nodes go to private subnets, internet-facing load balancers to public ones.
+func KubernetesSubnetTags(clusterName string, tier string) map[string]string {
+	if clusterName == "" {
+		return map[string]string{}
+	}
+
+	tags := map[string]string{
+		KubernetesClusterTagPrefix + clusterName: "shared",
+	}
+	switch tier {
+	case TierPrivate:
+		tags[KubernetesInternalElbRoleTag] = "1"
+		tags[KarpenterDiscoveryTag] = clusterName
+	case TierPublic:
+		tags[KubernetesElbRoleTag] = "1"
+	}
+
+	return tags
+}
+
 func cidrSubnet(vcpCidr string, azs []string) (privateCidrSubnets, publicSCidrSubnets []string, err error) {
 	_, base, err := net.ParseCIDR(vcpCidr)
 	if err != nil {
@@ -1,30 +1,51 @@
 package alb
 
 import (
-	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"fmt"
+
 	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
-	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/route53"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 
-	"github.com/tungnt76/pulumi-in-go/cloudflare/elb/alb/acm"
+	"github.com/tungnt76/pulumi-in-go/aws/certificate"
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+	listenerrule "github.com/tungnt76/pulumi-in-go/aws/elb/alb/listener_rule"
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+	"github.com/tungnt76/pulumi-in-go/dns"
 )
 
 type ALBArgs struct {
-	Name          string
-	CloudZoneName string
-	Environment   string
-	Domain        string
+	Name        string
+	Environment string
+	// Domain is required, it names the listener certificate and the alias
+	// record.
+	Domain string
 
-	VpcId            string
+	// Vpc is the output of vpc.CreateVpc. The load balancer is placed in its
+	// public subnets, or its private subnets when Internal.
+	Vpc              *vpc.VpcOutput
 	Internal         bool
 	SecurityGroupIDs []string
 	TargetGroupArn   string
-	Listener         *Listener
-	FixedResponse    *FixedResponse
+	// TargetGroups forwards the default action to several weighted target
+	// groups instead of TargetGroupArn.
+	TargetGroups  []*listenerrule.WeightedTargetGroup
+	Stickiness    *listenerrule.Stickiness
+	Listener      *Listener
+	FixedResponse *FixedResponse
+	Attributes    *Attributes
 
+	// Dns publishes the load balancer records and validates the
+	// certificate. Defaults to a Route53 provider for Route53HostedZone.
+	Dns               dns.Provider
 	Route53HostedZone string
 	ExtraDomains      []string
-	Proxied           bool
+	// Proxied only applies when Dns is a Cloudflare provider.
+	Proxied bool
+	// CAA is the CAA policy published on Domain, see caa.DefaultPolicy.
+	CAA *caa.Policy
+	// PrivateCA issues the listener certificate from an ACM Private CA,
+	// which internal load balancers on private domains need.
+	PrivateCA *certificate.PrivateCAOutput
 }
 
 type Listener struct {
@@ -39,9 +60,53 @@ type FixedResponse struct {
 	StatusCode  string
 }
 
-type ALBOutput struct{}
+// Attributes tunes the load balancer itself. Pointer fields are optional and
+// fall back to per-environment defaults when nil.
+type Attributes struct {
+	// DeletionProtection defaults to true for production environments.
+	DeletionProtection *bool
+	// IdleTimeout is the connection idle timeout in seconds (1-4000).
+	IdleTimeout int
+	EnableHttp2 *bool
+	// DropInvalidHeaderFields removes headers that are not valid HTTP header
+	// names before routing the request to targets.
+	DropInvalidHeaderFields bool
+	// DesyncMitigationMode is one of monitor, defensive or strictest.
+	DesyncMitigationMode string
+	// XffHeaderProcessingMode is one of append, preserve or remove.
+	XffHeaderProcessingMode string
+	EnableXffClientPort     bool
+	PreserveHostHeader      bool
+	// EnableWafFailOpen lets requests through when the associated WAF is
+	// unreachable instead of rejecting them.
+	EnableWafFailOpen bool
+}
+
+type ALBOutput struct {
+	LoadBalancerArn pulumi.StringOutput
+	DnsName         pulumi.StringOutput
+	ZoneId          pulumi.StringOutput
+	ListenerArn     pulumi.StringOutput
+}
+
+var _productionEnvironments = []string{"production", "prod"}
+
+var _desyncMitigationModes = []string{"monitor", "defensive", "strictest"}
+
+var _xffHeaderProcessingModes = []string{"append", "preserve", "remove"}
 
 func CreateALB(ctx *pulumi.Context, args *ALBArgs) (*ALBOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+	// the listener always serves the certificate issued for Domain
+	if args.Domain == "" {
+		return nil, fmt.Errorf("alb %s: domain cannot be empty", args.Name)
+	}
+	if args.Vpc == nil {
+		return nil, fmt.Errorf("alb %s: vpc cannot be nil", args.Name)
+	}
+
 	if args.Listener == nil {
 		args.Listener = &Listener{
 			Port:       443,
@@ -58,41 +123,61 @@ func CreateALB(ctx *pulumi.Context, args *ALBArgs) (*ALBOutput, error) {
 		}
 	}
 
-	ttl := 60
-	if args.Proxied {
-		ttl = 1
-	}
-
-	acmOutput, err := acm.CreateACM(ctx, &acm.ACMArgs{
-		CloudZoneName: args.CloudZoneName,
-		Environment:   args.Environment,
-		Domain:        args.Domain,
-	})
+	attributes, err := loadBalancerAttributes(args.Environment, args.Attributes)
 	if err != nil {
 		return nil, err
 	}
 
-	subnets, err := ec2.GetSubnets(ctx, &ec2.GetSubnetsArgs{
-		Filters: []ec2.GetSubnetsFilter{
-			{
-				Name: "vpc-id",
-				Values: []string{
-					args.VpcId,
-				},
-			},
-		},
-	})
+	if args.PrivateCA != nil && !args.Internal {
+		return nil, fmt.Errorf("alb %s: private ca certificates are only trusted by internal clients, set Internal", args.Name)
+	}
+
+	dnsProvider := args.Dns
+	if dnsProvider == nil {
+		dnsProvider, err = dns.NewRoute53Provider(ctx, args.Route53HostedZone, args.Internal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// one certificate covers Domain and every extra domain as a SAN
+	certificateArgs := &certificate.CertificateArgs{
+		Name:                    fmt.Sprintf("%s-cert", args.Name),
+		Domain:                  args.Domain,
+		SubjectAlternativeNames: args.ExtraDomains,
+		Environment:             args.Environment,
+		CAA:                     args.CAA,
+		PrivateCA:               args.PrivateCA,
+	}
+	if args.PrivateCA == nil {
+		certificateArgs.Dns = dnsProvider
+	}
+
+	certificateOutput, err := certificate.CreateCertificate(ctx, certificateArgs)
 	if err != nil {
 		return nil, err
 	}
 
+	subnetIds := args.Vpc.PublicSubnetIds
+	if args.Internal {
+		subnetIds = args.Vpc.PrivateSubnetIds
+	}
+
 	loadBalancer, err := lb.NewLoadBalancer(ctx, "alb", &lb.LoadBalancerArgs{
 		Name:                     pulumi.StringPtr(args.Name),
-		Internal:                 pulumi.Bool(false),
+		Internal:                 pulumi.Bool(args.Internal),
 		LoadBalancerType:         pulumi.String("application"),
-		Subnets:                  pulumi.ToStringArray(subnets.Ids),
+		Subnets:                  subnetIds,
 		SecurityGroups:           pulumi.ToStringArray(args.SecurityGroupIDs),
-		EnableDeletionProtection: pulumi.Bool(false),
+		EnableDeletionProtection: pulumi.Bool(*attributes.DeletionProtection),
+		IdleTimeout:              pulumi.Int(attributes.IdleTimeout),
+		EnableHttp2:              pulumi.Bool(*attributes.EnableHttp2),
+		DropInvalidHeaderFields:  pulumi.Bool(attributes.DropInvalidHeaderFields),
+		DesyncMitigationMode:     pulumi.String(attributes.DesyncMitigationMode),
+		XffHeaderProcessingMode:  pulumi.String(attributes.XffHeaderProcessingMode),
+		EnableXffClientPort:      pulumi.Bool(attributes.EnableXffClientPort),
+		PreserveHostHeader:       pulumi.Bool(attributes.PreserveHostHeader),
+		EnableWafFailOpen:        pulumi.Bool(attributes.EnableWafFailOpen),
 	})
 	if err != nil {
 		return nil, err
@@ -112,12 +197,36 @@ func CreateALB(ctx *pulumi.Context, args *ALBArgs) (*ALBOutput, error) {
 			TargetGroupArn: pulumi.String(args.TargetGroupArn),
 		}
 	}
+	if len(args.TargetGroups) > 0 {
+		targetGroups := lb.ListenerDefaultActionForwardTargetGroupArray{}
+		for _, targetGroup := range args.TargetGroups {
+			targetGroups = append(targetGroups, lb.ListenerDefaultActionForwardTargetGroupArgs{
+				Arn:    targetGroup.Arn,
+				Weight: pulumi.IntPtr(targetGroup.Weight),
+			})
+		}
+
+		forward := &lb.ListenerDefaultActionForwardArgs{
+			TargetGroups: targetGroups,
+		}
+		if args.Stickiness != nil {
+			forward.Stickiness = &lb.ListenerDefaultActionForwardStickinessArgs{
+				Enabled:  pulumi.BoolPtr(args.Stickiness.Enabled),
+				Duration: pulumi.Int(args.Stickiness.Duration),
+			}
+		}
+
+		action = &lb.ListenerDefaultActionArgs{
+			Type:    pulumi.String("forward"),
+			Forward: forward,
+		}
+	}
 	listener, err := lb.NewListener(ctx, "alb_listener", &lb.ListenerArgs{
 		LoadBalancerArn: loadBalancer.Arn,
 		Port:            pulumi.Int(args.Listener.Port),
 		Protocol:        pulumi.String(args.Listener.Protocol),
 		AlpnPolicy:      pulumi.String(args.Listener.AlpnPolicy),
-		CertificateArn:  acmOutput.CertificateArn,
+		CertificateArn:  certificateOutput.ValidatedCertificateArn,
 		SslPolicy:       pulumi.String("ELBSecurityPolicy-2016-08"),
 		DefaultActions: lb.ListenerDefaultActionArray{
 			action,
@@ -127,72 +236,79 @@ func CreateALB(ctx *pulumi.Context, args *ALBArgs) (*ALBOutput, error) {
 		return nil, err
 	}
 
-	for _, domain := range args.ExtraDomains {
-		acmOutput, err := acm.CreateACM(ctx, &acm.ACMArgs{
-			CloudZoneName: args.CloudZoneName,
-			Environment:   args.Environment,
-			Domain:        domain,
-		})
-		if err != nil {
-			return nil, err
+	domains := append([]string{args.Domain}, args.ExtraDomains...)
+	for index, domain := range domains {
+		name := "alb_record"
+		if index > 0 {
+			name = fmt.Sprintf("alb_record-%s", domain)
 		}
 
-		_, err = lb.NewListenerCertificate(ctx, "alb_listener_certificate", &lb.ListenerCertificateArgs{
-			ListenerArn:    listener.Arn,
-			CertificateArn: acmOutput.CertificateArn,
+		_, err = dnsProvider.CreateRecord(ctx, name, &dns.Record{
+			Domain: pulumi.String(domain),
+			Alias: &dns.Alias{
+				DnsName: loadBalancer.DnsName,
+				ZoneId:  loadBalancer.ZoneId,
+			},
+			Proxied: args.Proxied,
 		})
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	zone, err := route53.LookupZone(ctx, &route53.LookupZoneArgs{
-		Name:        pulumi.StringRef(args.Route53HostedZone),
-		PrivateZone: pulumi.BoolRef(false),
-	})
-	if err != nil {
-		return nil, err
+	return &ALBOutput{
+		LoadBalancerArn: loadBalancer.Arn,
+		DnsName:         loadBalancer.DnsName,
+		ZoneId:          loadBalancer.ZoneId,
+		ListenerArn:     listener.Arn,
+	}, nil
+}
+
+// loadBalancerAttributes fills in defaults for unset attributes and rejects
+// values the ELB API would refuse.
+func loadBalancerAttributes(environment string, attributes *Attributes) (*Attributes, error) {
+	result := Attributes{}
+	if attributes != nil {
+		result = *attributes
 	}
 
-	if args.Domain != "" {
-		_, err = route53.NewRecord(ctx, "alb_record", &route53.RecordArgs{
-			ZoneId: pulumi.String(zone.ZoneId),
-			Name:   pulumi.String(args.Domain),
-			Type:   pulumi.String(route53.RecordTypeCNAME),
-			Aliases: route53.RecordAliasArray{
-				&route53.RecordAliasArgs{
-					Name:   loadBalancer.DnsName,
-					ZoneId: loadBalancer.ZoneId,
-				},
-			},
-			Ttl:            pulumi.IntPtr(ttl),
-			AllowOverwrite: pulumi.Bool(true),
-		})
-		if err != nil {
-			return nil, err
-		}
+	if result.DeletionProtection == nil {
+		deletionProtection := contains(_productionEnvironments, environment)
+		result.DeletionProtection = &deletionProtection
+	}
+	if result.EnableHttp2 == nil {
+		enableHttp2 := true
+		result.EnableHttp2 = &enableHttp2
+	}
+	if result.IdleTimeout == 0 {
+		result.IdleTimeout = 60
+	}
+	if result.DesyncMitigationMode == "" {
+		result.DesyncMitigationMode = "defensive"
+	}
+	if result.XffHeaderProcessingMode == "" {
+		result.XffHeaderProcessingMode = "append"
 	}
 
-	if len(args.ExtraDomains) > 0 {
-		for _, domain := range args.ExtraDomains {
-			_, err = route53.NewRecord(ctx, "alb_record", &route53.RecordArgs{
-				ZoneId: pulumi.String(zone.ZoneId),
-				Name:   pulumi.String(domain),
-				Type:   pulumi.String(route53.RecordTypeCNAME),
-				Aliases: route53.RecordAliasArray{
-					&route53.RecordAliasArgs{
-						Name:   loadBalancer.DnsName,
-						ZoneId: loadBalancer.ZoneId,
-					},
-				},
-				Ttl:            pulumi.IntPtr(ttl),
-				AllowOverwrite: pulumi.Bool(true),
-			})
-			if err != nil {
-				return nil, err
-			}
+	if result.IdleTimeout < 1 || result.IdleTimeout > 4000 {
+		return nil, fmt.Errorf("idle timeout must be between 1 and 4000 seconds, got %d", result.IdleTimeout)
+	}
+	if !contains(_desyncMitigationModes, result.DesyncMitigationMode) {
+		return nil, fmt.Errorf("unsupported desync mitigation mode %q", result.DesyncMitigationMode)
+	}
+	if !contains(_xffHeaderProcessingModes, result.XffHeaderProcessingMode) {
+		return nil, fmt.Errorf("unsupported xff header processing mode %q", result.XffHeaderProcessingMode)
+	}
+
+	return &result, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
 
-	return nil, nil
+	return false
 }
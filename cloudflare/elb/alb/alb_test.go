@@ -0,0 +1,122 @@
+package alb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+	"github.com/tungnt76/pulumi-in-go/dns"
+)
+
+// mocks answers ACM certificates with one validation option per name and
+// echoes the inputs of every other resource.
+type mocks struct{}
+
+func (mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	outputs := args.Inputs.Copy()
+	switch args.TypeToken {
+	case "aws:acm/certificate:Certificate":
+		names := []string{args.Inputs["domainName"].StringValue()}
+		if sans, ok := args.Inputs["subjectAlternativeNames"]; ok {
+			for _, san := range sans.ArrayValue() {
+				names = append(names, san.StringValue())
+			}
+		}
+
+		options := []resource.PropertyValue{}
+		for _, name := range names {
+			options = append(options, resource.NewObjectProperty(resource.PropertyMap{
+				"domainName":          resource.NewStringProperty(name),
+				"resourceRecordName":  resource.NewStringProperty(fmt.Sprintf("_x.%s.", strings.TrimPrefix(name, "*."))),
+				"resourceRecordType":  resource.NewStringProperty("CNAME"),
+				"resourceRecordValue": resource.NewStringProperty("_y.acm-validations.aws."),
+			}))
+		}
+		outputs["domainValidationOptions"] = resource.NewArrayProperty(options)
+		outputs["arn"] = resource.NewStringProperty("arn:aws:acm:eu-west-1:123456789012:certificate/" + args.Name)
+	case "aws:lb/loadBalancer:LoadBalancer":
+		outputs["arn"] = resource.NewStringProperty("arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/" + args.Name + "/1")
+		outputs["dnsName"] = resource.NewStringProperty(args.Name + ".eu-west-1.elb.amazonaws.com")
+		outputs["zoneId"] = resource.NewStringProperty("Z32O12XQLNTSW2")
+	}
+
+	return args.Name + "-id", outputs, nil
+}
+
+func (mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestCreateALBRecords(t *testing.T) {
+	tests := []struct {
+		name         string
+		domain       string
+		extraDomains []string
+		want         []string
+		wantErr      string
+	}{
+		{
+			name:   "domain only",
+			domain: "example.com",
+			want:   []string{"alb_record"},
+		},
+		{
+			name:         "one record per extra domain",
+			domain:       "example.com",
+			extraDomains: []string{"www.example.com", "api.example.com"},
+			want:         []string{"alb_record", "alb_record-api.example.com", "alb_record-www.example.com"},
+		},
+		{
+			name:         "domain is required",
+			extraDomains: []string{"www.example.com"},
+			wantErr:      "domain cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := dns.NewFakeProvider("example.com")
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := CreateALB(ctx, &ALBArgs{
+					Name:         "web",
+					Environment:  "dev",
+					Domain:       tt.domain,
+					ExtraDomains: tt.extraDomains,
+					Vpc: &vpc.VpcOutput{
+						PublicSubnetIds:  pulumi.StringArray{pulumi.String("subnet-a"), pulumi.String("subnet-b")},
+						PrivateSubnetIds: pulumi.StringArray{pulumi.String("subnet-c"), pulumi.String("subnet-d")},
+					},
+					Dns: provider,
+				})
+				return err
+			}, pulumi.WithMocks("project", "stack", mocks{}))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for name, record := range provider.Records {
+				if record.Alias == nil {
+					continue
+				}
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got alias records %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package nlb
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/ec2"
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/lb"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate"
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+	targetgroup "github.com/tungnt76/pulumi-in-go/aws/elb/target-group"
+	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+	"github.com/tungnt76/pulumi-in-go/dns"
+)
+
+var _listenerProtocols = []string{"TCP", "UDP", "TCP_UDP", "TLS"}
+
+type NLBArgs struct {
+	Name         string
+	Environment  string
+	Domain       string
+	ExtraDomains []string
+	Tags         map[string]string
+
+	// Vpc is the output of vpc.CreateVpc. The load balancer is placed in its
+	// public subnets, or its private subnets when Internal.
+	Vpc              *vpc.VpcOutput
+	Internal         bool
+	SecurityGroupIDs []string
+	// StaticIps allocates one Elastic IP per public subnet so clients can
+	// allow-list the load balancer. Only valid for internet-facing NLBs.
+	StaticIps bool
+	CrossZone bool
+	Listeners []*Listener
+
+	// CertificateArn is used by TLS listeners. When empty a certificate is
+	// requested for Domain and validated through Dns.
+	CertificateArn string
+	// CAA is the CAA policy published on Domain, see caa.DefaultPolicy.
+	CAA *caa.Policy
+
+	// Dns publishes Domain and ExtraDomains; no records are created when nil.
+	Dns dns.Provider
+}
+
+type Listener struct {
+	Port     int
+	Protocol string
+	// SslPolicy and AlpnPolicy only apply to TLS listeners.
+	SslPolicy  string
+	AlpnPolicy string
+	// TargetGroup is created in the NLB's VPC and receives the listener's
+	// traffic.
+	TargetGroup targetgroup.TargetGroupArgs
+}
+
+type NLBOutput struct {
+	LoadBalancerArn pulumi.StringOutput
+	DnsName         pulumi.StringOutput
+	ZoneId          pulumi.StringOutput
+	ListenerArns    map[int]pulumi.StringOutput
+	TargetGroups    map[string]*targetgroup.TargetGroupOutput
+	StaticIps       pulumi.StringArray
+}
+
+func CreateNLB(ctx *pulumi.Context, args *NLBArgs) (*NLBOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("nlb %s: %w", args.Name, err)
+	}
+
+	subnetIds := args.Vpc.PublicSubnetIds
+	if args.Internal {
+		subnetIds = args.Vpc.PrivateSubnetIds
+	}
+
+	staticIps := pulumi.StringArray{}
+	subnetMappings := lb.LoadBalancerSubnetMappingArray{}
+	for index, subnetId := range subnetIds {
+		mapping := lb.LoadBalancerSubnetMappingArgs{
+			SubnetId: subnetId,
+		}
+
+		if args.StaticIps {
+			eip, err := ec2.NewEip(ctx, fmt.Sprintf("%s-nlb-eip-%d", args.Name, index+1), &ec2.EipArgs{
+				Domain: pulumi.String("vpc"),
+				Tags: pulumi.ToStringMap(
+					merge(map[string]string{
+						"Name": fmt.Sprintf("%s-nlb-eip-%d", args.Name, index+1),
+					}, args.Tags),
+				),
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			mapping.AllocationId = eip.AllocationId
+			staticIps = append(staticIps, eip.PublicIp)
+		}
+
+		subnetMappings = append(subnetMappings, mapping)
+	}
+
+	loadBalancer, err := lb.NewLoadBalancer(ctx, fmt.Sprintf("%s-nlb", args.Name), &lb.LoadBalancerArgs{
+		Name:                         pulumi.StringPtr(args.Name),
+		Internal:                     pulumi.Bool(args.Internal),
+		LoadBalancerType:             pulumi.String("network"),
+		SubnetMappings:               subnetMappings,
+		SecurityGroups:               pulumi.ToStringArray(args.SecurityGroupIDs),
+		EnableCrossZoneLoadBalancing: pulumi.Bool(args.CrossZone),
+		Tags: pulumi.ToStringMap(
+			merge(map[string]string{
+				"Name":        args.Name,
+				"Environment": args.Environment,
+			}, args.Tags),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var certificateArn pulumi.StringInput
+	if args.CertificateArn != "" {
+		certificateArn = pulumi.String(args.CertificateArn)
+	} else if hasTlsListener(args.Listeners) {
+		certificateOutput, err := certificate.CreateCertificate(ctx, &certificate.CertificateArgs{
+			Name:        fmt.Sprintf("%s-nlb-cert", args.Name),
+			Domain:      args.Domain,
+			Environment: args.Environment,
+			Tags:        args.Tags,
+			Dns:         args.Dns,
+			CAA:         args.CAA,
+		})
+		if err != nil {
+			return nil, err
+		}
+		certificateArn = certificateOutput.ValidatedCertificateArn
+	}
+
+	output := &NLBOutput{
+		LoadBalancerArn: loadBalancer.Arn,
+		DnsName:         loadBalancer.DnsName,
+		ZoneId:          loadBalancer.ZoneId,
+		ListenerArns:    map[int]pulumi.StringOutput{},
+		TargetGroups:    map[string]*targetgroup.TargetGroupOutput{},
+		StaticIps:       staticIps,
+	}
+
+	for _, listener := range args.Listeners {
+		targetGroupArgs := listener.TargetGroup
+		targetGroupArgs.VpcId = args.Vpc.VpcId.ToStringOutput()
+		targetGroup, err := targetgroup.CreateTargetGroup(ctx, &targetGroupArgs)
+		if err != nil {
+			return nil, err
+		}
+		output.TargetGroups[targetGroupArgs.Name] = targetGroup
+
+		listenerArgs := &lb.ListenerArgs{
+			LoadBalancerArn: loadBalancer.Arn,
+			Port:            pulumi.Int(listener.Port),
+			Protocol:        pulumi.String(listener.Protocol),
+			DefaultActions: lb.ListenerDefaultActionArray{
+				&lb.ListenerDefaultActionArgs{
+					Type:           pulumi.String("forward"),
+					TargetGroupArn: targetGroup.TargetGroupArn,
+				},
+			},
+		}
+		if listener.Protocol == "TLS" {
+			sslPolicy := listener.SslPolicy
+			if sslPolicy == "" {
+				sslPolicy = "ELBSecurityPolicy-TLS13-1-2-2021-06"
+			}
+			listenerArgs.CertificateArn = certificateArn
+			listenerArgs.SslPolicy = pulumi.String(sslPolicy)
+			if listener.AlpnPolicy != "" {
+				listenerArgs.AlpnPolicy = pulumi.String(listener.AlpnPolicy)
+			}
+		}
+
+		nlbListener, err := lb.NewListener(ctx, fmt.Sprintf("%s-nlb-listener-%d", args.Name, listener.Port), listenerArgs)
+		if err != nil {
+			return nil, err
+		}
+		output.ListenerArns[listener.Port] = nlbListener.Arn
+	}
+
+	domains := []string{}
+	if args.Domain != "" {
+		domains = append(domains, args.Domain)
+	}
+	domains = append(domains, args.ExtraDomains...)
+
+	if args.Dns != nil {
+		for _, domain := range domains {
+			// NLBs speak raw TCP/UDP, which Cloudflare cannot proxy
+			_, err = args.Dns.CreateRecord(ctx, fmt.Sprintf("%s-nlb-record-%s", args.Name, domain), &dns.Record{
+				Domain: pulumi.String(domain),
+				Alias: &dns.Alias{
+					DnsName: loadBalancer.DnsName,
+					ZoneId:  loadBalancer.ZoneId,
+				},
+				Ttl: 300,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return output, nil
+}
+
+func validate(args *NLBArgs) error {
+	if args.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if args.Vpc == nil {
+		return fmt.Errorf("vpc cannot be nil")
+	}
+	if len(args.Listeners) == 0 {
+		return fmt.Errorf("at least one listener is required")
+	}
+	if args.StaticIps && args.Internal {
+		return fmt.Errorf("static ips are only available for internet-facing load balancers")
+	}
+
+	ports := map[int]bool{}
+	for _, listener := range args.Listeners {
+		if !contains(_listenerProtocols, listener.Protocol) {
+			return fmt.Errorf("unsupported listener protocol %q", listener.Protocol)
+		}
+		if ports[listener.Port] {
+			return fmt.Errorf("port %d has more than one listener", listener.Port)
+		}
+		ports[listener.Port] = true
+
+		// TLS is terminated on the load balancer, so targets get TLS or TCP
+		switch listener.Protocol {
+		case "TLS":
+			if listener.TargetGroup.Protocol != "TLS" && listener.TargetGroup.Protocol != "TCP" {
+				return fmt.Errorf("tls listener on port %d needs a TLS or TCP target group, got %q", listener.Port, listener.TargetGroup.Protocol)
+			}
+		default:
+			if listener.TargetGroup.Protocol != listener.Protocol {
+				return fmt.Errorf("%s listener on port %d needs a %s target group, got %q", listener.Protocol, listener.Port, listener.Protocol, listener.TargetGroup.Protocol)
+			}
+		}
+	}
+
+	if hasTlsListener(args.Listeners) && args.CertificateArn == "" && (args.Domain == "" || args.Dns == nil) {
+		return fmt.Errorf("tls listeners need a certificate arn, or a domain and dns provider to request one")
+	}
+
+	return nil
+}
+
+func hasTlsListener(listeners []*Listener) bool {
+	for _, listener := range listeners {
+		if listener.Protocol == "TLS" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func merge[M ~map[K]V, K comparable, V any](m1 M, m2 M) M {
+	new := map[K]V{}
+	for k, v := range m1 {
+		new[k] = v
+	}
+	for k, v := range m2 {
+		new[k] = v
+	}
+
+	return new
+}
@@ -1,53 +1,283 @@
 package record
 
 import (
+	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/pulumi/pulumi-cloudflare/sdk/v5/go/cloudflare"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	// TXT strings longer than this are split into several quoted strings.
+	_txtChunkSize = 255
+
+	// a TTL of 1 lets Cloudflare pick it, and is required for proxied records
+	_automaticTtl = 1
+)
+
 type RecordArgs struct {
+	// Name is the logical resource name. CreateRecords derives one from the
+	// type and domain when empty.
+	Name    string
 	Proxied bool
 	Ttl     int
 	Type    string
 	Value   string
 	Domain  string
+
+	// Priority is used by MX records.
+	Priority int
+	Srv      *SrvData
+	Caa      *CaaData
+	Comment  string
+	Tags     []string
+}
+
+type SrvData struct {
+	Service  string
+	Proto    string
+	Priority int
+	Weight   int
+	Port     int
+	Target   string
+}
+
+type CaaData struct {
+	Flags int
+	Tag   string
+	Value string
 }
 
 type RecordOutput struct {
 	CloudflareZoneName string
+	CloudflareZoneId   string
+	Hostname           pulumi.StringOutput
+}
+
+type RecordsArgs struct {
+	// AccountId limits the zone lookup to one account.
+	AccountId string
+	Records   []*RecordArgs
+}
+
+type RecordsOutput struct {
+	Records []*RecordOutput
 }
 
 func CreateRecord(ctx *pulumi.Context, args *RecordArgs) (*RecordOutput, error) {
-	parts := strings.Split(args.Domain, ".")
-	zoneName := strings.Join(parts[len(parts)-2:], ".")
-	ttl := args.Ttl
-	if args.Proxied {
-		ttl = 1
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
 	}
 
-	zone, err := cloudflare.LookupZone(ctx, &cloudflare.LookupZoneArgs{
-		Name: pulumi.StringRef(zoneName),
+	record := *args
+	if record.Name == "" {
+		record.Name = "record"
+	}
+
+	output, err := CreateRecords(ctx, &RecordsArgs{
+		Records: []*RecordArgs{&record},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = cloudflare.NewRecord(ctx, "record", &cloudflare.RecordArgs{
-		ZoneId:         pulumi.String(zone.ZoneId),
-		Name:           pulumi.String(args.Domain),
-		Type:           pulumi.String(args.Type),
-		Proxied:        pulumi.Bool(args.Proxied),
-		Value:          pulumi.String(args.Value),
-		Ttl:            pulumi.Int(ttl),
-		AllowOverwrite: pulumi.Bool(true),
+	return output.Records[0], nil
+}
+
+func CreateRecords(ctx *pulumi.Context, args *RecordsArgs) (*RecordsOutput, error) {
+	if args == nil {
+		return nil, fmt.Errorf("args cannot be nil")
+	}
+
+	for _, record := range args.Records {
+		if err := validate(record); err != nil {
+			return nil, fmt.Errorf("record %s %s: %w", record.Type, record.Domain, err)
+		}
+	}
+
+	filter := cloudflare.GetZonesFilter{
+		Status: pulumi.StringRef("active"),
+	}
+	if args.AccountId != "" {
+		filter.AccountId = pulumi.StringRef(args.AccountId)
+	}
+	zones, err := cloudflare.GetZones(ctx, &cloudflare.GetZonesArgs{
+		Filter: filter,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &RecordOutput{
-		CloudflareZoneName: zoneName,
-	}, nil
+	names := logicalNames(args.Records)
+
+	output := &RecordsOutput{}
+	for index, args := range args.Records {
+		zone, ok := matchZone(zones.Zones, args.Domain)
+		if !ok {
+			return nil, fmt.Errorf("no cloudflare zone found for %s", args.Domain)
+		}
+
+		ttl := args.Ttl
+		if args.Proxied || ttl == 0 {
+			ttl = _automaticTtl
+		}
+
+		recordArgs := &cloudflare.RecordArgs{
+			ZoneId:         pulumi.String(*zone.Id),
+			Name:           pulumi.String(args.Domain),
+			Type:           pulumi.String(args.Type),
+			Proxied:        pulumi.Bool(args.Proxied),
+			Ttl:            pulumi.Int(ttl),
+			Tags:           pulumi.ToStringArray(args.Tags),
+			AllowOverwrite: pulumi.Bool(true),
+		}
+		if args.Comment != "" {
+			recordArgs.Comment = pulumi.String(args.Comment)
+		}
+
+		switch args.Type {
+		case "SRV":
+			recordArgs.Data = cloudflare.RecordDataArgs{
+				Service:  pulumi.String(args.Srv.Service),
+				Proto:    pulumi.String(args.Srv.Proto),
+				Priority: pulumi.Int(args.Srv.Priority),
+				Weight:   pulumi.Int(args.Srv.Weight),
+				Port:     pulumi.Int(args.Srv.Port),
+				Target:   pulumi.String(args.Srv.Target),
+			}
+		case "CAA":
+			recordArgs.Data = cloudflare.RecordDataArgs{
+				Flags: pulumi.String(fmt.Sprintf("%d", args.Caa.Flags)),
+				Tag:   pulumi.String(args.Caa.Tag),
+				Value: pulumi.String(args.Caa.Value),
+			}
+		case "MX":
+			recordArgs.Content = pulumi.String(args.Value)
+			recordArgs.Priority = pulumi.Int(args.Priority)
+		case "TXT":
+			recordArgs.Content = pulumi.String(txtContent(args.Value))
+		default:
+			recordArgs.Content = pulumi.String(args.Value)
+		}
+
+		record, err := cloudflare.NewRecord(ctx, names[index], recordArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		output.Records = append(output.Records, &RecordOutput{
+			CloudflareZoneName: *zone.Name,
+			CloudflareZoneId:   *zone.Id,
+			Hostname:           record.Hostname,
+		})
+	}
+
+	return output, nil
+}
+
+func validate(args *RecordArgs) error {
+	if args.Domain == "" {
+		return fmt.Errorf("domain cannot be empty")
+	}
+
+	switch args.Type {
+	case "SRV":
+		if args.Srv == nil {
+			return fmt.Errorf("srv records need srv data")
+		}
+	case "CAA":
+		if args.Caa == nil {
+			return fmt.Errorf("caa records need caa data")
+		}
+	default:
+		if args.Value == "" {
+			return fmt.Errorf("value cannot be empty")
+		}
+	}
+
+	if args.Proxied && args.Type != "A" && args.Type != "AAAA" && args.Type != "CNAME" {
+		return fmt.Errorf("only A, AAAA and CNAME records can be proxied")
+	}
+
+	return nil
+}
+
+// matchZone returns the zone with the longest name that domain belongs to, so
+// example.co.uk resolves to example.co.uk rather than co.uk.
+func matchZone(zones []cloudflare.GetZonesZone, domain string) (cloudflare.GetZonesZone, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	var match cloudflare.GetZonesZone
+	found := false
+	for _, zone := range zones {
+		if zone.Name == nil || zone.Id == nil {
+			continue
+		}
+
+		name := strings.ToLower(*zone.Name)
+		if domain != name && !strings.HasSuffix(domain, "."+name) {
+			continue
+		}
+		if !found || len(name) > len(*match.Name) {
+			match = zone
+			found = true
+		}
+	}
+
+	return match, found
+}
+
+// logicalNames keeps explicit names and numbers records that share a type
+// and domain, such as several MX records.
+func logicalNames(records []*RecordArgs) []string {
+	counts := map[string]int{}
+	for _, record := range records {
+		counts[fmt.Sprintf("%s-%s", strings.ToLower(record.Type), record.Domain)]++
+	}
+
+	names := []string{}
+	seen := map[string]int{}
+	for _, record := range records {
+		if record.Name != "" {
+			names = append(names, record.Name)
+			continue
+		}
+
+		key := fmt.Sprintf("%s-%s", strings.ToLower(record.Type), record.Domain)
+		seen[key]++
+		if counts[key] > 1 {
+			names = append(names, fmt.Sprintf("record-%s-%d", key, seen[key]))
+		} else {
+			names = append(names, fmt.Sprintf("record-%s", key))
+		}
+	}
+
+	return names
+}
+
+// txtContent splits long TXT values, such as DKIM keys, into quoted strings
+// of at most 255 bytes each. Chunks end on rune boundaries and only quotes and
+// backslashes are escaped, as zone files expect.
+func txtContent(value string) string {
+	if len(value) <= _txtChunkSize {
+		return value
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	chunks := []string{}
+	for len(value) > 0 {
+		size := len(value)
+		if size > _txtChunkSize {
+			size = _txtChunkSize
+			for size > 0 && !utf8.RuneStart(value[size]) {
+				size--
+			}
+		}
+		chunks = append(chunks, fmt.Sprintf(`"%s"`, escaper.Replace(value[:size])))
+		value = value[size:]
+	}
+
+	return strings.Join(chunks, " ")
 }
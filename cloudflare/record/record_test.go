@@ -0,0 +1,93 @@
+package record
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi-cloudflare/sdk/v5/go/cloudflare"
+)
+
+func zone(id, name string) cloudflare.GetZonesZone {
+	return cloudflare.GetZonesZone{Id: &id, Name: &name}
+}
+
+func TestMatchZone(t *testing.T) {
+	zones := []cloudflare.GetZonesZone{
+		zone("1", "example.com"),
+		zone("2", "dev.example.com"),
+		zone("3", "ample.com"),
+		{Name: nil},
+	}
+
+	tests := []struct {
+		name   string
+		domain string
+		wantId string
+	}{
+		{name: "apex", domain: "example.com", wantId: "1"},
+		{name: "subdomain", domain: "www.example.com", wantId: "1"},
+		{name: "longest suffix wins", domain: "api.dev.example.com", wantId: "2"},
+		{name: "delegated apex", domain: "dev.example.com", wantId: "2"},
+		{name: "label boundary", domain: "www.ample.com", wantId: "3"},
+		{name: "case and trailing dot", domain: "WWW.Example.COM.", wantId: "1"},
+		{name: "no match", domain: "example.org"},
+		{name: "suffix is not a label", domain: "notexample.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchZone(zones, tt.domain)
+			if tt.wantId == "" {
+				if ok {
+					t.Fatalf("got zone %s, want none", *got.Name)
+				}
+				return
+			}
+			if !ok || *got.Id != tt.wantId {
+				t.Fatalf("got zone %v, want %s", got.Id, tt.wantId)
+			}
+		})
+	}
+}
+
+func TestTxtContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "short values are kept",
+			value: `v=spf1 include:"x" -all`,
+			want:  `v=spf1 include:"x" -all`,
+		},
+		{
+			name:  "split at 255 bytes",
+			value: strings.Repeat("a", 300),
+			want:  `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `"`,
+		},
+		{
+			name:  "quotes and backslashes are escaped",
+			value: strings.Repeat("a", 254) + `"\`,
+			want:  `"` + strings.Repeat("a", 254) + `\"" "\\"`,
+		},
+		{
+			name:  "multi-byte characters are not split",
+			value: strings.Repeat("a", 254) + "é" + "b",
+			want:  `"` + strings.Repeat("a", 254) + `" "éb"`,
+		},
+		{
+			name:  "non-ascii is not escaped",
+			value: strings.Repeat("ü", 130),
+			want:  `"` + strings.Repeat("ü", 127) + `" "` + strings.Repeat("ü", 3) + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := txtContent(tt.value); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package dns
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi-cloudflare/sdk/v5/go/cloudflare"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+)
+
+const (
+	_cloudflareTtl = 60
+
+	// proxied records must use the automatic TTL
+	_cloudflareAutomaticTtl = 1
+)
+
+type CloudflareProvider struct {
+	ZoneId string
+	zone   string
+}
+
+func NewCloudflareProvider(ctx *pulumi.Context, zoneName string) (*CloudflareProvider, error) {
+	zone, err := cloudflare.LookupZone(ctx, &cloudflare.LookupZoneArgs{
+		Name: pulumi.StringRef(zoneName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CloudflareProvider{
+		ZoneId: zone.ZoneId,
+		zone:   zoneName,
+	}, nil
+}
+
+func (p *CloudflareProvider) Name() string {
+	return ProviderCloudflare
+}
+
+func (p *CloudflareProvider) ZoneName() string {
+	return p.zone
+}
+
+func (p *CloudflareProvider) CreateRecord(ctx *pulumi.Context, name string, record *Record) (pulumi.StringOutput, error) {
+	if err := validate(record); err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	ttl := record.Ttl
+	if ttl == 0 {
+		ttl = _cloudflareTtl
+	}
+	if record.Proxied {
+		ttl = _cloudflareAutomaticTtl
+	}
+
+	recordType := record.Type
+	content := record.Value
+	switch {
+	case record.Alias != nil:
+		// Cloudflare flattens CNAMEs at the apex, so a CNAME works everywhere
+		recordType = "CNAME"
+		content = record.Alias.DnsName
+	case recordType == "CNAME":
+		// Cloudflare rejects fully qualified targets such as the ones ACM
+		// returns for validation records
+		content = record.Value.ToStringOutput().ApplyT(func(value string) string {
+			return strings.TrimSuffix(value, ".")
+		}).(pulumi.StringOutput)
+	}
+
+	r, err := cloudflare.NewRecord(ctx, name, &cloudflare.RecordArgs{
+		ZoneId:         pulumi.String(p.ZoneId),
+		Name:           record.Domain,
+		Type:           pulumi.String(recordType),
+		Content:        content,
+		Ttl:            pulumi.Int(ttl),
+		Proxied:        pulumi.Bool(record.Proxied),
+		AllowOverwrite: pulumi.Bool(true),
+	})
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	return r.Hostname, nil
+}
+
+func (p *CloudflareProvider) CAARecords(ctx *pulumi.Context, name string, domain string, policy *caa.Policy) ([]pulumi.Resource, error) {
+	return caa.CreateCloudflareRecords(ctx, name, p.ZoneId, domain, policy)
+}
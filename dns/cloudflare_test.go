@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// mocks echoes the inputs of every resource back as its outputs and keeps
+// them by logical name.
+type mocks struct {
+	mu        sync.Mutex
+	resources map[string]resource.PropertyMap
+}
+
+func (m *mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.resources == nil {
+		m.resources = map[string]resource.PropertyMap{}
+	}
+	m.resources[args.Name] = args.Inputs
+
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+func TestCloudflareCreateRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		record      *Record
+		wantType    string
+		wantContent string
+	}{
+		{
+			name:        "cname target is trimmed",
+			record:      &Record{Domain: pulumi.String("_x.example.com"), Type: "CNAME", Value: pulumi.String("_y.acm-validations.aws.")},
+			wantType:    "CNAME",
+			wantContent: "_y.acm-validations.aws",
+		},
+		{
+			name:        "alias ignores the cname type",
+			record:      &Record{Domain: pulumi.String("www.example.com"), Type: "CNAME", Alias: &Alias{DnsName: pulumi.String("lb.elb.amazonaws.com"), ZoneId: pulumi.String("Z1")}},
+			wantType:    "CNAME",
+			wantContent: "lb.elb.amazonaws.com",
+		},
+		{
+			name:        "alias without a type",
+			record:      &Record{Domain: pulumi.String("example.com"), Alias: &Alias{DnsName: pulumi.String("lb.elb.amazonaws.com"), ZoneId: pulumi.String("Z1")}},
+			wantType:    "CNAME",
+			wantContent: "lb.elb.amazonaws.com",
+		},
+		{
+			name:        "txt is kept as is",
+			record:      &Record{Domain: pulumi.String("example.com"), Type: "TXT", Value: pulumi.String("v=spf1 -all")},
+			wantType:    "TXT",
+			wantContent: "v=spf1 -all",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				provider := &CloudflareProvider{ZoneId: "zone-id", zone: "example.com"}
+				_, err := provider.CreateRecord(ctx, "record", tt.record)
+				return err
+			}, pulumi.WithMocks("project", "stack", m))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			inputs := m.resources["record"]
+			if got := inputs["type"].StringValue(); got != tt.wantType {
+				t.Errorf("got type %q, want %q", got, tt.wantType)
+			}
+			if got := inputs["content"].StringValue(); got != tt.wantContent {
+				t.Errorf("got content %q, want %q", got, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+)
+
+const (
+	ProviderRoute53    = "route53"
+	ProviderCloudflare = "cloudflare"
+)
+
+// Provider publishes records in the DNS zone it was created for, including the
+// CAA and validation records ACM needs.
+type Provider interface {
+	// Name is route53 or cloudflare.
+	Name() string
+	// ZoneName is the zone the provider writes to.
+	ZoneName() string
+	// CreateRecord creates the record under the given logical name and
+	// returns its FQDN.
+	CreateRecord(ctx *pulumi.Context, name string, record *Record) (pulumi.StringOutput, error)
+	// CAARecords publishes the CAA policy for domain.
+	CAARecords(ctx *pulumi.Context, name string, domain string, policy *caa.Policy) ([]pulumi.Resource, error)
+}
+
+type Record struct {
+	// Domain takes an output for names only known after apply, such as ACM
+	// validation records.
+	Domain pulumi.StringInput
+	// Type is ignored for aliases, which are A records on Route53 and
+	// CNAMEs on Cloudflare.
+	Type  string
+	Value pulumi.StringInput
+	// Alias points Domain at a load balancer instead of Value.
+	Alias *Alias
+	// Ttl is in seconds; each provider picks a default when zero.
+	Ttl int
+	// Proxied only applies to Cloudflare.
+	Proxied bool
+}
+
+type Alias struct {
+	DnsName pulumi.StringInput
+	ZoneId  pulumi.StringInput
+}
+
+// ProviderFromConfig builds the provider named by `<namespace>:provider` for
+// the zone `<namespace>:zone`. `<namespace>:private_zone` selects a private
+// Route53 zone.
+func ProviderFromConfig(ctx *pulumi.Context, namespace string) (Provider, error) {
+	dnsConfig := config.New(ctx, namespace)
+	zone := dnsConfig.Require("zone")
+
+	switch provider := dnsConfig.Get("provider"); provider {
+	case ProviderRoute53:
+		return NewRoute53Provider(ctx, zone, dnsConfig.GetBool("private_zone"))
+	case ProviderCloudflare:
+		return NewCloudflareProvider(ctx, zone)
+	default:
+		return nil, fmt.Errorf("unsupported dns provider %q", provider)
+	}
+}
+
+func validate(record *Record) error {
+	if record == nil {
+		return fmt.Errorf("record cannot be nil")
+	}
+	if record.Domain == nil {
+		return fmt.Errorf("domain cannot be empty")
+	}
+	if record.Alias == nil && record.Value == nil {
+		return fmt.Errorf("record needs a value or an alias")
+	}
+	if record.Alias == nil && record.Type == "" {
+		return fmt.Errorf("record needs a type")
+	}
+
+	return nil
+}
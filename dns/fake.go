@@ -0,0 +1,69 @@
+package dns
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+)
+
+// FakeProvider keeps records in memory instead of creating resources, for
+// tests of modules that publish DNS records.
+type FakeProvider struct {
+	Zone string
+
+	mu      sync.Mutex
+	Records map[string]*Record
+	// CAA holds the published CAA policies by domain.
+	CAA map[string]*caa.Policy
+}
+
+func NewFakeProvider(zoneName string) *FakeProvider {
+	return &FakeProvider{
+		Zone:    zoneName,
+		Records: map[string]*Record{},
+		CAA:     map[string]*caa.Policy{},
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) ZoneName() string {
+	return p.Zone
+}
+
+func (p *FakeProvider) CreateRecord(ctx *pulumi.Context, name string, record *Record) (pulumi.StringOutput, error) {
+	if err := validate(record); err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.Records[name]; ok {
+		return pulumi.StringOutput{}, fmt.Errorf("duplicate record name %q", name)
+	}
+	p.Records[name] = record
+
+	return record.Domain.ToStringOutput(), nil
+}
+
+func (p *FakeProvider) CAARecords(ctx *pulumi.Context, name string, domain string, policy *caa.Policy) ([]pulumi.Resource, error) {
+	if _, err := caa.Records(policy); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.CAA[domain]; ok {
+		return nil, fmt.Errorf("caa records for %s are already published", domain)
+	}
+	p.CAA[domain] = policy
+
+	return nil, nil
+}
@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"github.com/pulumi/pulumi-aws/sdk/v6/go/aws/route53"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/tungnt76/pulumi-in-go/aws/certificate/caa"
+)
+
+const _route53Ttl = 300
+
+type Route53Provider struct {
+	ZoneId string
+	zone   string
+}
+
+func NewRoute53Provider(ctx *pulumi.Context, zoneName string, privateZone bool) (*Route53Provider, error) {
+	zone, err := route53.LookupZone(ctx, &route53.LookupZoneArgs{
+		Name:        pulumi.StringRef(zoneName),
+		PrivateZone: pulumi.BoolRef(privateZone),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Route53Provider{
+		ZoneId: zone.ZoneId,
+		zone:   zoneName,
+	}, nil
+}
+
+func (p *Route53Provider) Name() string {
+	return ProviderRoute53
+}
+
+func (p *Route53Provider) ZoneName() string {
+	return p.zone
+}
+
+func (p *Route53Provider) CreateRecord(ctx *pulumi.Context, name string, record *Record) (pulumi.StringOutput, error) {
+	if err := validate(record); err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	args := &route53.RecordArgs{
+		ZoneId:         pulumi.String(p.ZoneId),
+		Name:           record.Domain,
+		AllowOverwrite: pulumi.Bool(true),
+	}
+
+	if record.Alias != nil {
+		// alias records take their TTL from the target
+		args.Type = pulumi.String(route53.RecordTypeA)
+		args.Aliases = route53.RecordAliasArray{
+			&route53.RecordAliasArgs{
+				Name:                 record.Alias.DnsName,
+				ZoneId:               record.Alias.ZoneId,
+				EvaluateTargetHealth: pulumi.Bool(true),
+			},
+		}
+	} else {
+		ttl := record.Ttl
+		if ttl == 0 {
+			ttl = _route53Ttl
+		}
+
+		args.Type = pulumi.String(record.Type)
+		args.Records = pulumi.StringArray{record.Value}
+		args.Ttl = pulumi.Int(ttl)
+	}
+
+	r, err := route53.NewRecord(ctx, name, args)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	return r.Fqdn, nil
+}
+
+func (p *Route53Provider) CAARecords(ctx *pulumi.Context, name string, domain string, policy *caa.Policy) ([]pulumi.Resource, error) {
+	return caa.CreateRoute53Records(ctx, name, p.ZoneId, domain, policy)
+}
@@ -9,9 +9,14 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+	"github.com/tungnt76/pulumi-in-go/aws/certificate"
 	cloudflareprefixlists "github.com/tungnt76/pulumi-in-go/aws/cloudflare-prefix-lists"
+	"github.com/tungnt76/pulumi-in-go/aws/ecr"
+	"github.com/tungnt76/pulumi-in-go/aws/eks"
+	"github.com/tungnt76/pulumi-in-go/aws/rds"
 	securitygroup "github.com/tungnt76/pulumi-in-go/aws/security-group"
 	"github.com/tungnt76/pulumi-in-go/aws/vpc"
+	"github.com/tungnt76/pulumi-in-go/dns"
 )
 
 func main() {
@@ -37,6 +42,8 @@ func main() {
 	w := s.Workspace()
 	w.InstallPlugin(ctx, "aws", "v6.56.0")
 	w.InstallPlugin(ctx, "cloudflare", "v5.40.1")
+	w.InstallPlugin(ctx, "kubernetes", "v4.18.1")
+	w.InstallPlugin(ctx, "random", "v4.16.7")
 
 	_, err = s.Refresh(ctx)
 	if err != nil {
@@ -77,12 +84,16 @@ func createVpcWithSG(ctx *pulumi.Context) error {
 	azs := []string{}
 	vpcConfig.GetObject("azs", &azs)
 
+	eksConfig := config.New(ctx, "eks")
+	clusterName := eksConfig.Get("name")
+
 	vpcArgs := &vpc.VpcArgs{
 		Name:              vpcConfig.Get("name"),
 		Cidr:              vpcConfig.Get("cidr"),
 		Tags:              map[string]string{},
 		PrivateSubnetTags: map[string]string{},
 		PublicSubnetTags:  map[string]string{},
+		ClusterName:       clusterName,
 	}
 
 	vpcOutput, err := vpc.CreateVpc(ctx, vpcArgs)
@@ -91,7 +102,151 @@ func createVpcWithSG(ctx *pulumi.Context) error {
 		os.Exit(1)
 	}
 
-	pulumi.All(vpcOutput.VpcId, l.Ipv4ManagedId, l.Ipv6ManagedId).ApplyT(func(args []interface{}) error {
+	if clusterName != "" {
+		publicAccessCidrs := []string{}
+		eksConfig.GetObject("public_access_cidrs", &publicAccessCidrs)
+
+		cluster, err := eks.CreateCluster(ctx, &eks.ClusterArgs{
+			Name:              clusterName,
+			Version:           eksConfig.Get("version"),
+			Environment:       config.Get(ctx, "environment"),
+			Vpc:               vpcOutput,
+			EndpointAccess:    eksConfig.Get("endpoint_access"),
+			PublicAccessCidrs: publicAccessCidrs,
+			PrivateAccessCidrs: []string{
+				vpcConfig.Get("cidr"),
+			},
+		})
+		if err != nil {
+			fmt.Printf("Failed to create EKS cluster: %v\n", err)
+			os.Exit(1)
+		}
+
+		groups, err := eks.NodeGroupsFromConfig(ctx, "eks")
+		if err != nil {
+			fmt.Printf("Failed to read EKS node groups: %v\n", err)
+			os.Exit(1)
+		}
+
+		nodeGroups, err := eks.CreateNodeGroups(ctx, &eks.NodeGroupsArgs{
+			Cluster:     cluster,
+			Environment: config.Get(ctx, "environment"),
+			Groups:      groups,
+		})
+		if err != nil {
+			fmt.Printf("Failed to create EKS node groups: %v\n", err)
+			os.Exit(1)
+		}
+
+		// IRSA roles of add-ons and workloads share the cluster's one OIDC
+		// provider
+		oidcProvider, err := eks.CreateOidcProvider(ctx, cluster, map[string]string{})
+		if err != nil {
+			fmt.Printf("Failed to create EKS OIDC provider: %v\n", err)
+			os.Exit(1)
+		}
+
+		addonConfigs, err := eks.AddonsFromConfig(ctx, "eks")
+		if err != nil {
+			fmt.Printf("Failed to read EKS add-ons: %v\n", err)
+			os.Exit(1)
+		}
+
+		addons, err := eks.CreateAddons(ctx, &eks.AddonsArgs{
+			Cluster:      cluster,
+			Environment:  config.Get(ctx, "environment"),
+			Addons:       addonConfigs,
+			OidcProvider: oidcProvider,
+			Nodes:        nodeGroups.Resources,
+		})
+		if err != nil {
+			fmt.Printf("Failed to create EKS add-ons: %v\n", err)
+			os.Exit(1)
+		}
+
+		// a fresh slice so the two installs below never share, and overwrite,
+		// the node groups' backing array
+		deps := append(append([]pulumi.Resource{}, nodeGroups.Resources...), addons.Resources...)
+
+		if eksConfig.GetBool("load_balancer_controller") {
+			_, err = eks.CreateLoadBalancerController(ctx, &eks.LoadBalancerControllerArgs{
+				Cluster:     cluster,
+				Environment: config.Get(ctx, "environment"),
+				DependsOn:   deps,
+			})
+			if err != nil {
+				fmt.Printf("Failed to install AWS Load Balancer Controller: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if eksConfig.GetBool("karpenter") {
+			nodeClasses, err := eks.NodeClassesFromConfig(ctx, "karpenter")
+			if err != nil {
+				fmt.Printf("Failed to read Karpenter node classes: %v\n", err)
+				os.Exit(1)
+			}
+			nodePools, err := eks.NodePoolsFromConfig(ctx, "karpenter")
+			if err != nil {
+				fmt.Printf("Failed to read Karpenter node pools: %v\n", err)
+				os.Exit(1)
+			}
+
+			_, err = eks.CreateKarpenter(ctx, &eks.KarpenterArgs{
+				Cluster:     cluster,
+				Environment: config.Get(ctx, "environment"),
+				DependsOn:   deps,
+				NodeClasses: nodeClasses,
+				NodePools:   nodePools,
+			})
+			if err != nil {
+				fmt.Printf("Failed to install Karpenter: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		serviceAccounts, err := eks.ServiceAccountsFromConfig(ctx, "eks")
+		if err != nil {
+			fmt.Printf("Failed to read EKS service accounts: %v\n", err)
+			os.Exit(1)
+		}
+
+		_, err = eks.CreateServiceAccountRoles(ctx, &eks.ServiceAccountsArgs{
+			Cluster:         cluster,
+			OidcProvider:    oidcProvider,
+			Environment:     config.Get(ctx, "environment"),
+			ServiceAccounts: serviceAccounts,
+		})
+		if err != nil {
+			fmt.Printf("Failed to create EKS service account roles: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	repositories, err := ecr.RepositoriesFromConfig(ctx, "ecr")
+	if err != nil {
+		fmt.Printf("Failed to read ECR repositories: %v\n", err)
+		os.Exit(1)
+	}
+	pullThroughCacheRules, err := ecr.PullThroughCacheRulesFromConfig(ctx, "ecr")
+	if err != nil {
+		fmt.Printf("Failed to read ECR pull through cache rules: %v\n", err)
+		os.Exit(1)
+	}
+	if len(repositories) > 0 || len(pullThroughCacheRules) > 0 {
+		_, err = ecr.CreateRepositories(ctx, &ecr.RepositoriesArgs{
+			Environment:           config.Get(ctx, "environment"),
+			KmsKeyArn:             config.New(ctx, "ecr").Get("kms_key_arn"),
+			Repositories:          repositories,
+			PullThroughCacheRules: pullThroughCacheRules,
+		})
+		if err != nil {
+			fmt.Printf("Failed to create ECR repositories: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	appSecurityGroupId := pulumi.All(vpcOutput.VpcId, l.Ipv4ManagedId, l.Ipv6ManagedId).ApplyT(func(args []interface{}) (string, error) {
 		vpcId := args[0].(pulumi.ID)
 		ipv4ManagedId := args[1].(pulumi.ID)
 		ipv6ManagedId := args[2].(pulumi.ID)
@@ -101,7 +256,7 @@ func createVpcWithSG(ctx *pulumi.Context) error {
 		ingress := []*securitygroup.IngressRule{}
 		sgConfig.GetObject("ingress", &ingress)
 
-		_, err := securitygroup.CreateSecurityGroup(
+		sg, err := securitygroup.CreateSecurityGroup(
 			ctx,
 			&securitygroup.SecurityGroupArgs{
 				Name:                 name,
@@ -116,16 +271,55 @@ func createVpcWithSG(ctx *pulumi.Context) error {
 			os.Exit(1)
 		}
 
-		return nil
-	})
+		return sg.SecurityGroupID, nil
+	}).(pulumi.StringOutput)
+
+	rdsConfig := config.New(ctx, "rds")
+	if rdsConfig.Get("name") != "" {
+		_, err = rds.CreateDatabase(ctx, &rds.DatabaseArgs{
+			Name:               rdsConfig.Get("name"),
+			Environment:        config.Get(ctx, "environment"),
+			Vpc:                vpcOutput,
+			AppSecurityGroupId: appSecurityGroupId,
+			EngineVersion:      rdsConfig.Get("engine_version"),
+			InstanceClass:      rdsConfig.Get("instance_class"),
+			MultiAz:            rdsConfig.GetBool("multi_az"),
+			PasswordStorage:    rdsConfig.Get("password_storage"),
+		})
+		if err != nil {
+			fmt.Printf("Failed to create RDS database: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// fakeAcm, err := acm.CreateACM(ctx, &acm.ACMArgs{CloudZoneName: "example.com", Environment: "dev", Domain: "example.com"})
-	// if err != nil {
-	// 	fmt.Printf("Failed to create ACM: %v\n", err)
-	// 	os.Exit(1)
-	// }
+	certificateConfig := config.New(ctx, "certificate")
+	if certificateConfig.Get("domain") != "" {
+		dnsProvider, err := dns.ProviderFromConfig(ctx, "dns")
+		if err != nil {
+			fmt.Printf("Failed to create DNS provider: %v\n", err)
+			os.Exit(1)
+		}
 
-	// fmt.Println(fakeAcm)
+		subjectAlternativeNames := []string{}
+		err = certificateConfig.GetObject("subject_alternative_names", &subjectAlternativeNames)
+		if err != nil {
+			fmt.Printf("Failed to read certificate subject alternative names: %v\n", err)
+			os.Exit(1)
+		}
+
+		_, err = certificate.CreateCertificate(ctx, &certificate.CertificateArgs{
+			Name:                    certificateConfig.Get("name"),
+			Domain:                  certificateConfig.Get("domain"),
+			SubjectAlternativeNames: subjectAlternativeNames,
+			Wildcard:                certificateConfig.GetBool("wildcard"),
+			Environment:             config.Get(ctx, "environment"),
+			Dns:                     dnsProvider,
+		})
+		if err != nil {
+			fmt.Printf("Failed to create ACM certificate: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	return nil
 }